@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// isTarArchive reports whether the path names a tar archive, optionally gzip compressed
+func isTarArchive(p string) bool {
+	lower := strings.ToLower(p)
+	return strings.HasSuffix(lower, ".tar") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// openTar opens the archive for reading, decompressing it first when it is gzip compressed
+func openTar(p string) (*tar.Reader, io.Closer, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.HasSuffix(strings.ToLower(p), ".tar") {
+		return tar.NewReader(f), f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to decompress %s: %w", p, err)
+	}
+	return tar.NewReader(gz), f, nil
+}
+
+// selectMember reports whether the archive member is loaded: it must match the --member pattern, by
+// its path in the archive or its base name, or have a data file extension when no pattern is given
+func selectMember(name string, pattern string) bool {
+	if pattern == "" {
+		// The name is a path in the archive, so only its extension counts, not a local file of that name
+		return formatByExtension(name) != ""
+	}
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, path.Base(name))
+	return ok
+}
+
+// extractTar writes the selected regular files of the archive into a directory below tempDir, keeping
+// their paths in the archive, and returns the extracted files in archive order. Members with a path
+// leaving the directory are rejected.
+func extractTar(archive string, tempDir string, pattern string) ([]string, error) {
+	r, closer, err := openTar(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	dir, err := os.MkdirTemp(tempDir, "tar-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the extraction directory: %w", err)
+	}
+	var files []string
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", archive, err)
+		}
+		if header.Typeflag != tar.TypeReg || !selectMember(header.Name, pattern) {
+			continue
+		}
+		if !filepath.IsLocal(header.Name) {
+			return nil, fmt.Errorf("%s: refusing to extract %s, which is outside the archive", archive, header.Name)
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+			return nil, err
+		}
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+		_, err = io.Copy(out, r)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+		files = append(files, dst)
+	}
+	return files, nil
+}
+
+// prepareTarInput extracts the data files of a tar archive into tempDir and reads them as one input,
+// like the files matched by a pattern. All extracted files must have the same format, --member picks
+// some of them when they do not.
+func prepareTarInput(cmd *cobra.Command, input inputTable, tempDir string) (inputTable, error) {
+	pattern := cmd.Flag("member").Value.String()
+	files, err := extractTar(input.path, tempDir, pattern)
+	if err != nil {
+		return input, err
+	}
+	if len(files) == 0 {
+		if pattern != "" {
+			return input, fmt.Errorf("no member of %s matches --member '%s'", input.path, pattern)
+		}
+		return input, fmt.Errorf("%s contains no Parquet, CSV, JSON or Arrow files", input.path)
+	}
+
+	if format := cmd.Flag("format").Value.String(); format != "" {
+		if input.fileFormat, err = parseFileFormat(format); err != nil {
+			return input, err
+		}
+	} else {
+		var formats []string
+		for _, f := range files {
+			format := string(determineFileFormat(f))
+			if format == "" {
+				return input, fmt.Errorf("unsupported file format for %s in %s, pass --format", filepath.Base(f), input.path)
+			}
+			if !slices.Contains(formats, format) {
+				formats = append(formats, format)
+			}
+		}
+		if len(formats) > 1 {
+			return input, fmt.Errorf("%s contains %s files, pick the ones to load with --member", input.path, strings.Join(formats, " and "))
+		}
+		input.fileFormat = FileFormat(formats[0])
+	}
+	fmt.Fprintf(statusOut, "Detected file format: %s (%s)\n", input.fileFormat, input.path)
+	fmt.Fprintf(statusOut, "Extracted %d file(s) from %s\n", len(files), input.path)
+
+	// Members compressed with bzip2 or xz are decompressed like files given as arguments
+	for i, f := range files {
+		if needsDecompression(f) {
+			if files[i], err = decompressFile(f, tempDir); err != nil {
+				return input, err
+			}
+		}
+	}
+	input.files = files
+	return configureInput(cmd, input, tempDir)
+}
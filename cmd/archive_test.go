@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    bool
+	}{
+		{"data.parquet", "", true},
+		{"export/orders.csv", "", true},
+		{"export/orders.csv.gz", "", true},
+		{"events.ndjson", "", true},
+		{"README.md", "", false},
+		{"export/", "", false},
+		{"export/_delta_log/00000.json", "", true},
+		{"export/orders.csv", "*.csv", true},
+		{"export/orders.csv", "export/*.csv", true},
+		{"export/orders.csv", "*.parquet", false},
+		{"export/2024/orders.csv", "export/*.csv", false},
+		{"notes.txt", "notes.*", true},
+	}
+	for _, tt := range tests {
+		if got := selectMember(tt.name, tt.pattern); got != tt.want {
+			t.Errorf("selectMember(%q, %q) = %v, want %v", tt.name, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSelectMemberIgnoresLocalFiles(t *testing.T) {
+	// A local Delta table with the name of a member must not change how the member is classified
+	dir := t.TempDir()
+	writeTestFile(t, dir, "table.csv/_delta_log/00000000000000000000.json", "{}")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if determineFileFormat("table.csv") != Delta {
+		t.Fatal("the local directory is not detected as a Delta table")
+	}
+	if !selectMember("table.csv", "") {
+		t.Error("a CSV member is skipped because a local directory of its name is a Delta table")
+	}
+}
+
+// tarMember is an entry of a test archive, a symlink when link is set
+type tarMember struct {
+	name     string
+	contents string
+	link     string
+}
+
+// writeTestTar builds a tar archive of the members in memory and writes it to dir
+func writeTestTar(t *testing.T, dir string, name string, members []tarMember) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, m := range members {
+		header := &tar.Header{Name: m.name, Mode: 0o644, Size: int64(len(m.contents)), Typeflag: tar.TypeReg}
+		if m.link != "" {
+			header = &tar.Header{Name: m.name, Mode: 0o777, Linkname: m.link, Typeflag: tar.TypeSymlink}
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(m.contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return writeTestFile(t, dir, name, buf.String())
+}
+
+func TestExtractTar(t *testing.T) {
+	dir := t.TempDir()
+	archive := writeTestTar(t, dir, "data.tar", []tarMember{
+		{name: "b.csv", contents: "a\n2\n"},
+		{name: "README.md", contents: "notes"},
+		{name: "export/a.csv", contents: "a\n1\n"},
+		// The symlink is not extracted, so the member below it is written to a real directory
+		{name: "link", link: dir},
+		{name: "link/c.csv", contents: "a\n3\n"},
+	})
+	tempDir := filepath.Join(dir, "tmp")
+	if err := os.Mkdir(tempDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := extractTar(archive, tempDir, "")
+	if err != nil {
+		t.Fatalf("extractTar() error = %v", err)
+	}
+	dirs, _ := filepath.Glob(filepath.Join(tempDir, "tar-*"))
+	if len(dirs) != 1 {
+		t.Fatalf("extraction directories = %q, want one", dirs)
+	}
+	var names []string
+	for _, f := range files {
+		rel, err := filepath.Rel(dirs[0], f)
+		if err != nil || !filepath.IsLocal(rel) {
+			t.Errorf("%s is extracted outside of %s", f, dirs[0])
+		}
+		if info, err := os.Lstat(filepath.Dir(f)); err != nil || !info.IsDir() {
+			t.Errorf("the directory of %s is not a real directory", f)
+		}
+		names = append(names, filepath.ToSlash(rel))
+	}
+	if want := []string{"b.csv", "export/a.csv", "link/c.csv"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("extractTar() = %q, want %q", names, want)
+	}
+	if contents, err := os.ReadFile(files[1]); err != nil || string(contents) != "a\n1\n" {
+		t.Errorf("export/a.csv holds %q, %v", contents, err)
+	}
+}
+
+func TestExtractTarRejectsUnsafeMembers(t *testing.T) {
+	tests := []struct {
+		name   string
+		member string
+		wantIn string
+	}{
+		{"parent directory", "../evil.csv", "outside the archive"},
+		{"nested parent directory", "export/../../evil.csv", "outside the archive"},
+		{"absolute path", "/tmp/evil.csv", "outside the archive"},
+		{"duplicate name", "data.csv", "failed to extract data.csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := writeTestTar(t, dir, "evil.tar", []tarMember{
+				{name: "data.csv", contents: "a\n1\n"},
+				{name: tt.member, contents: "a\nevil\n"},
+			})
+			tempDir := filepath.Join(dir, "tmp")
+			if err := os.Mkdir(tempDir, 0o700); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := extractTar(archive, tempDir, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantIn) {
+				t.Fatalf("extractTar() error = %v, want an error containing %q", err, tt.wantIn)
+			}
+			for _, d := range []string{dir, tempDir} {
+				if fileExists(filepath.Join(d, "evil.csv")) {
+					t.Errorf("the member was written to %s, outside the extraction directory", d)
+				}
+			}
+			// The first member is not replaced by the duplicate
+			matches, _ := filepath.Glob(filepath.Join(tempDir, "tar-*", "data.csv"))
+			if len(matches) != 1 {
+				t.Fatalf("data.csv was not extracted: %q", matches)
+			}
+			if contents, err := os.ReadFile(matches[0]); err != nil || string(contents) != "a\n1\n" {
+				t.Errorf("data.csv holds %q, %v after the rejected member", contents, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// duckdbMagic is written by DuckDB at offset 8 of its database files. SQLite files often use the same
+// .db extension, so the extension alone does not tell them apart.
+const duckdbMagic = "DUCK"
+
+// isDuckDBDatabase reports whether the path names a DuckDB database file, by its extension
+func isDuckDBDatabase(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".duckdb", ".db":
+		return true
+	default:
+		return false
+	}
+}
+
+// checkDuckDBDatabase returns an error unless the file starts with the DuckDB header
+func checkDuckDBDatabase(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[8:]) != duckdbMagic {
+		return fmt.Errorf("%s is not a DuckDB database", path)
+	}
+	return nil
+}
+
+// attachStatements returns the statements attaching the database read-only and making it the default,
+// so its tables can be queried by their plain names
+func attachStatements(path string) string {
+	alias := quoteIdentifier(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	return fmt.Sprintf("ATTACH %s AS %s (READ_ONLY); USE %s;", quoteLiteral(path), alias, alias)
+}
+
+// runDatabaseSession opens an existing DuckDB database instead of loading files into a table. The database
+// is attached read-only, so inspecting it never changes it, and its own tables take the place of p.
+// setup holds the session statements to run before the attach, and execQuery the --exec query as
+// runCommand built it, so --dialect and --distinct apply to it as they do for files.
+func runDatabaseSession(cmd *cobra.Command, path string, tempDir string, setup string, execQuery string) error {
+	for _, name := range batchModeFlags {
+		if name != "exec" && flagGiven(cmd, name) {
+			return fmt.Errorf("--%s is not supported for DuckDB databases", name)
+		}
+	}
+	if err := checkDuckDBDatabase(path); err != nil {
+		return err
+	}
+	attach := setup + attachStatements(path)
+
+	// A query runs after the attach statements in the same -c argument, the session gets
+	// them from the init script
+	var commands []string
+	if execQuery == "" {
+		if cmd.Flag("no-banner").Value.String() != "true" {
+			banner, err := databaseBanner(path, attach)
+			if err != nil {
+				return err
+			}
+			commands = append(commands, banner...)
+		}
+		commands = append(commands, attach, promptCommand(cmd, path))
+	}
+	width, err := cmd.Flags().GetInt("width")
+	if err != nil || width < 0 {
+		return fmt.Errorf("--width must be a positive number")
+	}
+	if w := outputWidth(width); w > 0 {
+		commands = append(commands, fmt.Sprintf(".maxwidth %d", w))
+	}
+	if startQuery := cmd.Flag("start-query").Value.String(); startQuery != "" {
+		commands = append(commands, ".echo on", trimStatement(startQuery)+";", ".echo off")
+	}
+	initFile, err := writeInitFile(tempDir, commands)
+	if err != nil {
+		return err
+	}
+
+	outputArgs := []string{"-init", initFile}
+	if cmd.Flag("transpose").Value.String() == "true" {
+		outputArgs = append(outputArgs, "-line")
+	}
+	outputFormat, err := parseOutputFormat(cmd.Flag("output-format").Value.String())
+	if err != nil {
+		return err
+	}
+	outputArgs = append(outputArgs, outputFormatArgs(outputFormat)...)
+
+	if execQuery != "" {
+		cmds := append([]string{"duckdb"}, outputArgs...)
+		if err := executeCommand(append(cmds, "-c", attach+" "+execQuery)); err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+		return nil
+	}
+
+	fmt.Fprintln(statusOut, "============== Starting DuckDB CLI ==============")
+	if err := executeCommand(append([]string{"duckdb"}, outputArgs...)); err != nil {
+		return fmt.Errorf("failed to execute DuckDB: %w", err)
+	}
+	return nil
+}
+
+// databaseBanner returns the init script commands listing the tables of the attached database with
+// their column and row counts, like tableBanner does for the loaded tables
+func databaseBanner(path string, attach string) ([]string, error) {
+	output, err := captureCommand([]string{"duckdb", "-csv", "-noheader", "-c", attach +
+		" SELECT schema_name, table_name, column_count, estimated_size FROM duckdb_tables() WHERE database_name = current_database() ORDER BY schema_name, table_name;"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the tables of %s: %w", path, err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the tables of %s: %w", path, err)
+	}
+
+	lines := []string{fmt.Sprintf(".print Database %s: %d table(s), opened read-only", path, len(records))}
+	var first string
+	for _, r := range records {
+		if len(r) != 4 {
+			return nil, fmt.Errorf("unexpected table row: %v", r)
+		}
+		// Tables outside the main schema need their schema in queries
+		name := r[1]
+		if r[0] != "main" {
+			name = r[0] + "." + r[1]
+		}
+		if first == "" {
+			first = name
+		}
+		lines = append(lines, fmt.Sprintf(".print Table %s: %s row(s), %s column(s)", name, r[3], r[2]))
+	}
+	if first != "" {
+		lines = append(lines, fmt.Sprintf(".print Try: SELECT * FROM %s LIMIT 10;", first))
+	}
+	return append(lines, ".print"), nil
+}
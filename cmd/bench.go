@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench <file or pattern>",
+	Short: "Time loading and querying a file with DuckDB",
+	Long: `Time loading and querying a file with DuckDB.
+
+Each operation runs in a fresh duckdb process, so the timings include process startup. The startup row
+measures an empty query so it can be subtracted when comparing machines or DuckDB versions.`,
+	Example: `  dpi bench data.parquet
+  dpi bench --runs 5 '*.parquet'`,
+	Args: cobra.ExactArgs(1),
+	Run:  runBenchCommand,
+}
+
+func init() {
+	benchCmd.Flags().Int("runs", 3, "Number of times each operation is run")
+	rootCmd.AddCommand(benchCmd)
+}
+
+// benchResult holds the timings of one benchmarked operation
+type benchResult struct {
+	name  string
+	times []time.Duration
+	rows  int64 // rows processed per run, used for the throughput
+	bytes int64 // bytes read per run, used for the throughput
+}
+
+func (r benchResult) best() time.Duration {
+	best := r.times[0]
+	for _, t := range r.times[1:] {
+		best = min(best, t)
+	}
+	return best
+}
+
+func (r benchResult) mean() time.Duration {
+	var total time.Duration
+	for _, t := range r.times {
+		total += t
+	}
+	return total / time.Duration(len(r.times))
+}
+
+func (r benchResult) throughput() string {
+	seconds := r.best().Seconds()
+	if seconds == 0 || (r.rows == 0 && r.bytes == 0) {
+		return "-"
+	}
+	s := fmt.Sprintf("%s rows/s", formatCount(float64(r.rows)/seconds))
+	if r.bytes > 0 {
+		s += fmt.Sprintf(", %s/s", formatBytes(int64(float64(r.bytes)/seconds)))
+	}
+	return s
+}
+
+// timeRuns runs fn the given number of times and records each duration
+func timeRuns(runs int, fn func() error) ([]time.Duration, error) {
+	times := make([]time.Duration, 0, runs)
+	for range runs {
+		start := time.Now()
+		if err := fn(); err != nil {
+			return nil, err
+		}
+		times = append(times, time.Since(start))
+	}
+	return times, nil
+}
+
+func runBenchCommand(cmd *cobra.Command, args []string) {
+	runs, err := cmd.Flags().GetInt("runs")
+	if err != nil || runs < 1 {
+		exitWithError("--runs must be a positive number")
+	}
+
+	filePath := args[0]
+	fileFormat := determineFileFormat(filePath)
+	if fileFormat == "" {
+		exitWithError("Unsupported file format for file: %s", filePath)
+	}
+
+	tempDir, err := createTempDirectory("")
+	if err != nil {
+		exitWithError("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	duckdbPath := filepath.Join(tempDir, "tmp.duckdb")
+
+	files, err := processInputFiles(filePath, fileFormat, tempDir, false)
+	if err != nil {
+		exitWithError("%v", err)
+	}
+	filename := toFileNameString(files)
+
+	var inputBytes int64
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			inputBytes += info.Size()
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Benchmarking %d file(s), %s, %d run(s) each\n", len(files), formatBytes(inputBytes), runs)
+
+	var results []benchResult
+
+	startup, err := timeRuns(runs, func() error {
+		_, err := captureCommand([]string{"duckdb", "-c", "SELECT 1;"})
+		return err
+	})
+	if err != nil {
+		exitWithError("Benchmark failed: %v", err)
+	}
+	results = append(results, benchResult{name: "startup", times: startup})
+
+	// Every load run starts from an empty database so the table can be created again
+	load, err := timeRuns(runs, func() error {
+		if err := os.Remove(duckdbPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return createTemporaryTable(TableName, filename, duckdbPath, fileFormat, tableOptions{})
+	})
+	if err != nil {
+		exitWithError("Benchmark failed: %v", err)
+	}
+
+	count, err := queryScalar(duckdbPath, fmt.Sprintf("SELECT count(*) FROM %s;", TableName))
+	if err != nil {
+		exitWithError("Benchmark failed: %v", err)
+	}
+	rows, err := strconv.ParseInt(count, 10, 64)
+	if err != nil {
+		exitWithError("Benchmark failed: unexpected row count %q", count)
+	}
+	results = append(results, benchResult{name: "load", times: load, rows: rows, bytes: inputBytes})
+
+	queries := []struct{ name, sql string }{
+		{"count(*)", fmt.Sprintf("SELECT count(*) FROM %s;", TableName)},
+		{"group by", fmt.Sprintf("SELECT #1, count(*) FROM %s GROUP BY #1;", TableName)},
+	}
+	for _, q := range queries {
+		times, err := timeRuns(runs, func() error {
+			_, err := captureCommand([]string{"duckdb", duckdbPath, "-c", q.sql})
+			return err
+		})
+		if err != nil {
+			exitWithError("Benchmark failed: %v", err)
+		}
+		results = append(results, benchResult{name: q.name, times: times, rows: rows})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OPERATION\tBEST\tMEAN\tTHROUGHPUT")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.name, r.best().Round(time.Millisecond),
+			r.mean().Round(time.Millisecond), r.throughput())
+	}
+	w.Flush()
+	fmt.Fprintf(os.Stdout, "\n%d rows\n", rows)
+}
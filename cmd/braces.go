@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// expandBraces expands shell style alternatives such as "data_{2023,2024}.parquet" into one pattern per
+// alternative. Braces may be nested, and "{1..3}" or "{a..c}" expand to a range like in bash, where
+// "{01..10}" pads the numbers to the same width. Braces without a top-level comma, such as "{}" or "{a}", and
+// unmatched braces are kept literally, like a shell does. A backslash escapes the next character.
+func expandBraces(pattern string) []string {
+	open, close, alternatives := findBraces(pattern)
+	if open < 0 {
+		return []string{pattern}
+	}
+
+	prefix, suffix := pattern[:open], pattern[close+1:]
+	var expanded []string
+	for _, alt := range alternatives {
+		// The suffix may hold braces of its own, which the recursion expands
+		expanded = append(expanded, expandBraces(prefix+alt+suffix)...)
+	}
+	return expanded
+}
+
+// findBraces returns the positions of the first brace pair that has a top-level comma or holds a range,
+// along with its alternatives, or -1 when there is none
+func findBraces(pattern string) (int, int, []string) {
+	for start := 0; start < len(pattern); start++ {
+		if pattern[start] == '\\' {
+			start++
+			continue
+		}
+		if pattern[start] != '{' {
+			continue
+		}
+
+		depth := 0
+		var commas []int
+		for i := start; i < len(pattern); i++ {
+			switch pattern[i] {
+			case '\\':
+				i++
+			case '{':
+				depth++
+			case ',':
+				if depth == 1 {
+					commas = append(commas, i)
+				}
+			case '}':
+				depth--
+				if depth > 0 {
+					continue
+				}
+				if len(commas) == 0 {
+					if alternatives, ok := braceRange(pattern[start+1 : i]); ok {
+						return start, i, alternatives
+					}
+					// Literal braces, look for alternatives further on
+					i = len(pattern)
+					continue
+				}
+				alternatives := make([]string, 0, len(commas)+1)
+				from := start + 1
+				for _, c := range commas {
+					alternatives = append(alternatives, pattern[from:c])
+					from = c + 1
+				}
+				alternatives = append(alternatives, pattern[from:i])
+				return start, i, alternatives
+			}
+		}
+	}
+	return -1, -1, nil
+}
+
+// braceRange expands the body of a "{1..3}" or "{a..c}" range, counting down when the end is smaller
+// than the start. It reports false when the body is not a range.
+func braceRange(body string) ([]string, bool) {
+	from, to, ok := strings.Cut(body, "..")
+	if !ok {
+		return nil, false
+	}
+
+	if len(from) == 1 && len(to) == 1 && isLetter(from[0]) && isLetter(to[0]) {
+		var letters []string
+		for c := int(from[0]); ; c += rangeStep(int(from[0]), int(to[0])) {
+			letters = append(letters, string(rune(c)))
+			if c == int(to[0]) {
+				return letters, true
+			}
+		}
+	}
+
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return nil, false
+	}
+	end, err := strconv.Atoi(to)
+	if err != nil {
+		return nil, false
+	}
+	// Like bash, a leading zero on either end pads every number to the longer of the two
+	width := 0
+	if zeroPadded(from) || zeroPadded(to) {
+		width = max(len(from), len(to))
+	}
+	var numbers []string
+	for n := start; ; n += rangeStep(start, end) {
+		numbers = append(numbers, fmt.Sprintf("%0*d", width, n))
+		if n == end {
+			return numbers, true
+		}
+	}
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// rangeStep returns the increment counting from start towards end
+func rangeStep(start, end int) int {
+	if end < start {
+		return -1
+	}
+	return 1
+}
+
+// zeroPadded reports whether a range end is written with a leading zero, such as "01" or "-01"
+func zeroPadded(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	return len(s) > 1 && s[0] == '0'
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		// No braces
+		{"data.parquet", []string{"data.parquet"}},
+		{"data/*.parquet", []string{"data/*.parquet"}},
+		{"", []string{""}},
+
+		// Alternatives
+		{"data_{2023,2024}.parquet", []string{"data_2023.parquet", "data_2024.parquet"}},
+		{"{a,b}/{c,d}", []string{"a/c", "a/d", "b/c", "b/d"}},
+		{"x{a,b,c}", []string{"xa", "xb", "xc"}},
+
+		// Nested braces
+		{"{a,b{1,2}}.csv", []string{"a.csv", "b1.csv", "b2.csv"}},
+		{"{a,{b,{c,d}}}", []string{"a", "b", "c", "d"}},
+		{"{{a}, b}", []string{"{a}", " b"}},
+
+		// Empty alternatives
+		{"data{,_old}.parquet", []string{"data.parquet", "data_old.parquet"}},
+		{"x{,}", []string{"x", "x"}},
+
+		// Braces without a comma are literal, alternatives after them still expand
+		{"{}", []string{"{}"}},
+		{"{a}.parquet", []string{"{a}.parquet"}},
+		{"{a}_{b,c}", []string{"{a}_b", "{a}_c"}},
+
+		// Ranges
+		{"part-{1..3}", []string{"part-1", "part-2", "part-3"}},
+		{"{3..1}", []string{"3", "2", "1"}},
+		{"{-1..1}", []string{"-1", "0", "1"}},
+		{"{08..11}", []string{"08", "09", "10", "11"}},
+		{"{1..03}", []string{"01", "02", "03"}},
+		{"{a..c}", []string{"a", "b", "c"}},
+		{"{C..A}", []string{"C", "B", "A"}},
+		{"{2..2}", []string{"2"}},
+		{"m={1..2}/{x,y}", []string{"m=1/x", "m=1/y", "m=2/x", "m=2/y"}},
+		{"{a..3}", []string{"{a..3}"}},
+		{"{1..}", []string{"{1..}"}},
+		{"{ab..c}", []string{"{ab..c}"}},
+
+		// Unmatched braces are literal
+		{"{a,b", []string{"{a,b"}},
+		{"a,b}", []string{"a,b}"}},
+		{"{a,b}}", []string{"a}", "b}"}},
+		{"{{a,b}", []string{"{a", "{b"}},
+
+		// Escaped braces and commas stay in the pattern for filepath.Glob to unescape
+		{`\{a,b}`, []string{`\{a,b}`}},
+		{`{a\,b,c}`, []string{`a\,b`, "c"}},
+		{`{a,b\}`, []string{`{a,b\}`}},
+	}
+	for _, tt := range tests {
+		if got := expandBraces(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandBraces(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
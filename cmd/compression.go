@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// DuckDB reads gzip and zstd compressed CSV and JSON files itself. Other compressions are decompressed by dpi into the
+// temporary directory before loading.
+var decompressors = map[string]func(io.Reader) (io.Reader, error){
+	".bz2": func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil },
+	".xz":  func(r io.Reader) (io.Reader, error) { return xz.NewReader(r) },
+}
+
+// compressionSuffix returns the lower-cased compression extension of the file name, or "" for none
+func compressionSuffix(path string) string {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".gz", ".zst", ".zstd", ".bz2", ".xz":
+		return ext
+	default:
+		return ""
+	}
+}
+
+// stripCompressionSuffix returns the file name without its compression extension, so the extension
+// of the contents can be told, e.g. data.csv for data.csv.zst
+func stripCompressionSuffix(path string) string {
+	if compressionSuffix(path) == "" {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// readCompression returns the compression option of read_csv and read_json for a file DuckDB
+// decompresses itself. DuckDB only recognizes the .gz and .zst extensions, so the option is passed
+// explicitly.
+func readCompression(path string) string {
+	switch compressionSuffix(path) {
+	case ".gz":
+		return "gzip"
+	case ".zst", ".zstd":
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+// needsDecompression reports whether the file uses a compression DuckDB cannot read directly
+func needsDecompression(path string) bool {
+	_, ok := decompressors[compressionSuffix(path)]
+	return ok
+}
+
+// decompressFile writes the decompressed contents of the file into tempDir and returns the path of the copy.
+// The copy keeps the name without the compression suffix, with a random part so inputs with the same base
+// name do not collide.
+func decompressFile(path string, tempDir string) (string, error) {
+	suffix := compressionSuffix(path)
+	newReader, ok := decompressors[suffix]
+	if !ok {
+		return "", fmt.Errorf("unsupported compression for file: %s", path)
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	r, err := newReader(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	ext := filepath.Ext(name)
+	dst, err := os.CreateTemp(tempDir, strings.TrimSuffix(name, ext)+"-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create decompressed copy: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, r); err != nil {
+		return "", fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("failed to write decompressed copy: %w", err)
+	}
+	return dst.Name(), nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/ulikunitz/xz"
+)
+
+func TestCompressionSuffixes(t *testing.T) {
+	tests := []struct {
+		path     string
+		suffix   string
+		stripped string
+		read     string
+	}{
+		{"data.csv", "", "data.csv", ""},
+		{"data.csv.gz", ".gz", "data.csv", "gzip"},
+		{"data.CSV.GZ", ".gz", "data.CSV", "gzip"},
+		{"data.csv.zst", ".zst", "data.csv", "zstd"},
+		{"data.csv.zstd", ".zstd", "data.csv", "zstd"},
+		{"data.csv.bz2", ".bz2", "data.csv", ""},
+		{"data.csv.xz", ".xz", "data.csv", ""},
+		{"dir.gz/data.csv", "", "dir.gz/data.csv", ""},
+		{"data.zip", "", "data.zip", ""},
+	}
+	for _, tt := range tests {
+		if got := compressionSuffix(tt.path); got != tt.suffix {
+			t.Errorf("compressionSuffix(%s) = %q, want %q", tt.path, got, tt.suffix)
+		}
+		if got := stripCompressionSuffix(tt.path); got != tt.stripped {
+			t.Errorf("stripCompressionSuffix(%s) = %q, want %q", tt.path, got, tt.stripped)
+		}
+		if got := readCompression(tt.path); got != tt.read {
+			t.Errorf("readCompression(%s) = %q, want %q", tt.path, got, tt.read)
+		}
+	}
+}
+
+func TestNeedsDecompression(t *testing.T) {
+	for path, want := range map[string]bool{"a.csv.bz2": true, "a.csv.xz": true, "a.csv.gz": false, "a.csv.zst": false, "a.csv": false} {
+		if got := needsDecompression(path); got != want {
+			t.Errorf("needsDecompression(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestDetermineFileFormatCompressedCSV(t *testing.T) {
+	tests := map[string]FileFormat{
+		"data.csv.gz":      CSV,
+		"data.csv.zst":     CSV,
+		"data.csv.zstd":    CSV,
+		"data.csv.bz2":     CSV,
+		"data.csv.xz":      CSV,
+		"dump.txt.zst":     CSV,
+		"data.gz":          CSV, // no inner extension, read as delimited text
+		"data.parquet.gz":  "",
+		"data.arrow.zst":   "",
+		"data.feather.bz2": "",
+	}
+	for path, want := range tests {
+		if got := determineFileFormat(path); got != want {
+			t.Errorf("determineFileFormat(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestBuildSelectQueryCSVCompression(t *testing.T) {
+	for compression, want := range map[string]string{
+		"":     "SELECT * FROM read_csv('a.csv.zst', strict_mode=false)",
+		"zstd": "SELECT * FROM read_csv('a.csv.zst', strict_mode=false, compression='zstd')",
+		"gzip": "SELECT * FROM read_csv('a.csv.zst', strict_mode=false, compression='gzip')",
+	} {
+		got, err := buildSelectQuery(toFileNameString([]string{"a.csv.zst"}), CSV, tableOptions{compression: compression})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("query = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestDecompressFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv.xz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := xz.NewWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("a,b\n1,2\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	copyPath, err := decompressFile(path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base := filepath.Base(copyPath); !strings.HasPrefix(base, "data-") || filepath.Ext(base) != ".csv" {
+		t.Errorf("copy %s does not keep the inner name and extension", base)
+	}
+	if data, err := os.ReadFile(copyPath); err != nil || string(data) != "a,b\n1,2\n" {
+		t.Errorf("copy = %q, %v", data, err)
+	}
+	if _, err := decompressFile(filepath.Join(dir, "data.csv.gz"), dir); err == nil {
+		t.Error("decompressFile accepted a compression DuckDB reads itself")
+	}
+}
+
+func TestDetermineFileFormatCompressedJSON(t *testing.T) {
+	for _, path := range []string{"events.json.gz", "events.ndjson.gz", "events.jsonl.gz", "events.NDJSON.GZ", "events.ndjson.zst", "events.json.bz2"} {
+		if got := determineFileFormat(path); got != JSON {
+			t.Errorf("determineFileFormat(%s) = %q, want %q", path, got, JSON)
+		}
+	}
+}
+
+func TestPrepareInputGzippedNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.ndjson.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := gzip.NewWriter(f)
+	w.Write([]byte("{\"id\": 1}\n{\"id\": 2}\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	cmd := &cobra.Command{}
+	addReadFlags(cmd)
+	input, err := prepareInput(cmd, path, dir, tableOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.fileFormat != JSON || input.opts.compression != "gzip" {
+		t.Fatalf("format %q with compression %q, want gzip compressed JSON", input.fileFormat, input.opts.compression)
+	}
+	query, err := buildSelectQuery(input.filename(), input.fileFormat, input.opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT * FROM read_json('" + path + "', compression='gzip')"; query != want {
+		t.Errorf("query = %s, want %s", query, want)
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert <file or pattern> -o <output>",
+	Short: "Convert the files into a Parquet, CSV or JSON file",
+	Long: `Read the files like dpi does and write them to a single output file with DuckDB's COPY. The output
+format follows the extension of the output file: .parquet, .csv, .json or .ndjson, optionally followed
+by .gz or .zst for CSV and JSON. An existing output file is only replaced with --on-conflict overwrite.`,
+	Example: `  dpi convert data.csv -o data.parquet
+  dpi convert --output-compression zstd 'logs/*.parquet' -o logs.parquet
+  dpi convert -a data.parquet -o data.csv.gz`,
+	Args: cobra.ExactArgs(1),
+	Run:  runConvertCommand,
+}
+
+func init() {
+	addExportFlags(convertCmd)
+	rootCmd.AddCommand(convertCmd)
+}
+
+// addExportFlags registers the flags of the commands writing an output file
+func addExportFlags(cmd *cobra.Command) {
+	addReadFlags(cmd)
+	cmd.Flags().StringP("output", "o", "", "File to write, its extension selects the format")
+	cmd.Flags().String("output-compression", "", "Compression of the output: snappy, zstd, gzip, lz4, brotli or none for Parquet, gzip, zstd or none for CSV and JSON")
+	cmd.Flags().Bool("quote-all", false, "Quote every field of a CSV output, not only those that need it")
+	cmd.Flags().String("on-conflict", string(OnConflictFail), "What to do when the output exists: fail, overwrite or skip")
+	cmd.MarkFlagRequired("output")
+}
+
+func runConvertCommand(cmd *cobra.Command, args []string) {
+	runExport(cmd, args)
+}
+
+// runExport reads every argument like dpi does and writes their rows to the --output file with a single
+// COPY. Several arguments are concatenated in order, matching their columns by name with --union-by-name.
+func runExport(cmd *cobra.Command, args []string) {
+	statusOut = os.Stderr
+
+	output := cmd.Flag("output").Value.String()
+	format, err := exportFormatFor(output)
+	if err != nil {
+		exitWithError("%v", err)
+	}
+	if dir := filepath.Dir(output); !fileExists(dir) {
+		exitWithError("cannot write %s: directory %s does not exist", output, dir)
+	}
+	quoteAll := cmd.Flag("quote-all").Value.String() == "true"
+	if quoteAll && format != ExportCSV {
+		exitWithError("--quote-all is only supported for CSV output")
+	}
+	onConflict, err := parseOnConflict(cmd.Flag("on-conflict").Value.String())
+	if err != nil {
+		exitWithError("%v", err)
+	}
+	// Never asks, so scripts and jobs without a terminal behave the same as an interactive run
+	if fileExists(output) {
+		switch onConflict {
+		case OnConflictFail:
+			exitWithError("%s already exists, pass --on-conflict overwrite to replace it or skip to keep it", output)
+		case OnConflictSkip:
+			fmt.Fprintf(os.Stderr, "Skipped %s, it already exists\n", output)
+			return
+		}
+	}
+	compression := cmd.Flag("output-compression").Value.String()
+	if compression != "" {
+		if err := checkExportCompression(output, format, compression); err != nil {
+			exitWithError("%v", err)
+		}
+	}
+
+	opts, err := readOptions(cmd)
+	if err != nil {
+		exitWithError("%v", err)
+	}
+
+	tempDir, err := createTempDirectory(cmd.Flag("temp-dir").Value.String())
+	if err != nil {
+		exitWithError("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var setup []string
+	var queries []string
+	for _, path := range args {
+		input, err := prepareInput(cmd, path, tempDir, opts)
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		// A pattern like *.parquet would pick up the output of an earlier run
+		if err := checkNotInput(output, input.files); err != nil {
+			exitWithError("%v", err)
+		}
+		query, err := buildSelectQuery(input.filename(), input.fileFormat, input.opts)
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		if s := sessionSetup(input.fileFormat, input.opts); !slices.Contains(setup, s) {
+			setup = append(setup, s)
+		}
+		queries = append(queries, query)
+	}
+	union := " UNION ALL "
+	if opts.unionByName {
+		union = " UNION ALL BY NAME "
+	}
+
+	statement := loadSettings(opts) + strings.Join(setup, "") + copyStatement(strings.Join(queries, union), output, format, compression, quoteAll)
+	if err := executeCommand([]string{"duckdb", "-c", statement}); err != nil {
+		exitWithCommandError(fmt.Errorf("failed to write %s: %w", output, err))
+	}
+
+	if info, err := os.Stat(output); err == nil {
+		fmt.Fprintf(os.Stderr, "Wrote %s (%s)\n", output, formatBytes(info.Size()))
+	}
+}
+
+// checkNotInput returns an error if the output path is one of the input files
+func checkNotInput(output string, files []string) error {
+	out, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if abs, err := filepath.Abs(f); err == nil && abs == out {
+			return fmt.Errorf("the output %s is also one of the input files", output)
+		}
+	}
+	return nil
+}
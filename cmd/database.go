@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// tableExists reports whether the database already has a table with the given name
+func tableExists(duckdbPath string, tableName string) (bool, error) {
+	count, err := queryScalar(duckdbPath, fmt.Sprintf(
+		"SELECT count(*) FROM duckdb_tables() WHERE lower(table_name) = lower(%s) AND schema_name = current_schema();",
+		quoteLiteral(tableName)))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect %s: %w", duckdbPath, err)
+	}
+	return count != "0", nil
+}
+
+// removeDatabase deletes a database file created by this run, along with its write-ahead log
+func removeDatabase(path string) {
+	for _, f := range []string{path, path + ".wal"} {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", f, err)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Removed the partially created database %s\n", path)
+}
+
+// confirmOverwrite asks whether an existing table may be replaced. Without a terminal to ask on,
+// replacing requires --force.
+func confirmOverwrite(duckdbPath string, tableName string, force bool) error {
+	if force {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("table %s already exists in %s, use --force to replace it", tableName, duckdbPath)
+	}
+
+	fmt.Fprintf(os.Stderr, "Table %s already exists in %s. Replace it? [y/N] ", tableName, duckdbPath)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return fmt.Errorf("not replacing table %s in %s", tableName, duckdbPath)
+	}
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect is the query language of --exec queries
+type Dialect string
+
+const (
+	DialectSQL  Dialect = "sql"
+	DialectPRQL Dialect = "prql"
+)
+
+// parseDialect parses the --dialect flag
+func parseDialect(s string) (Dialect, error) {
+	switch d := Dialect(strings.ToLower(s)); d {
+	case DialectSQL, DialectPRQL:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unsupported dialect '%s' (expected %s or %s)", s, DialectSQL, DialectPRQL)
+	}
+}
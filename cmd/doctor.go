@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorExtensions are the DuckDB extensions dpi relies on for some inputs
+var doctorExtensions = []string{"parquet", "json", "httpfs", "delta", "iceberg"}
+
+var doctorCmd = &cobra.Command{
+	Use:     "doctor",
+	Aliases: []string{"env"},
+	Short:   "Print environment details for bug reports",
+	Long: `Print the dpi version, the DuckDB binary and version, the platform, the relevant environment
+variables and which DuckDB extensions are available. Include this output when reporting an issue.
+--json prints the same details as a JSON object for tools collecting them.`,
+	Example: `  dpi doctor
+  dpi doctor --json`,
+	Args: cobra.NoArgs,
+	// A missing DuckDB is one of the things doctor reports, so it must not stop it from running
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		applyErrorFormat(cmd)
+		return nil
+	},
+	Run: runDoctorCommand,
+}
+
+func init() {
+	doctorCmd.Flags().Bool("json", false, "Print the details as a JSON object")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// extensionStatus is the state of a DuckDB extension as reported by duckdb_extensions()
+type extensionStatus struct {
+	Name        string `json:"name"`
+	Installed   bool   `json:"installed"`
+	Loaded      bool   `json:"loaded"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+// doctorReport collects the environment details printed by dpi doctor. DuckDBPath is empty when
+// duckdb is not in PATH.
+type doctorReport struct {
+	Version       string            `json:"dpi_version"`
+	DuckDBPath    string            `json:"duckdb_path"`
+	DuckDBVersion string            `json:"duckdb_version"`
+	OS            string            `json:"os"`
+	Arch          string            `json:"arch"`
+	Env           map[string]string `json:"env"`
+	Extensions    []extensionStatus `json:"extensions"`
+}
+
+// isSecretEnv reports whether the variable may hold a credential and must not be printed
+func isSecretEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, word := range []string{"SECRET", "PASSWORD", "TOKEN", "KEY"} {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// relevantEnv returns the environment variables that affect dpi or DuckDB, with secrets masked
+func relevantEnv() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		switch {
+		case strings.HasPrefix(name, envPrefix), strings.HasPrefix(name, "DUCKDB_"),
+			name == "XDG_CONFIG_HOME", name == "TMPDIR":
+		default:
+			continue
+		}
+		if isSecretEnv(name) {
+			value = "********"
+		}
+		env[name] = value
+	}
+	return env
+}
+
+// extensionStatuses queries DuckDB for the state of the given extensions, or of every extension
+// DuckDB knows about when no names are given
+func extensionStatuses(names []string) ([]extensionStatus, error) {
+	query := "SELECT extension_name, installed, loaded, coalesce(extension_version, ''), coalesce(description, '') FROM duckdb_extensions()"
+	if len(names) > 0 {
+		quoted := make([]string, 0, len(names))
+		for _, n := range names {
+			quoted = append(quoted, quoteLiteral(n))
+		}
+		query += fmt.Sprintf(" WHERE extension_name IN (%s)", strings.Join(quoted, ", "))
+	}
+	query += " ORDER BY extension_name;"
+
+	output, err := captureCommand([]string{"duckdb", "-csv", "-noheader", "-c", query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list extensions: %w", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse extensions: %w", err)
+	}
+
+	statuses := make([]extensionStatus, 0, len(records))
+	for _, r := range records {
+		if len(r) < 5 {
+			continue
+		}
+		statuses = append(statuses, extensionStatus{Name: r[0], Installed: r[1] == "true", Loaded: r[2] == "true", Version: r[3], Description: r[4]})
+	}
+	return statuses, nil
+}
+
+func collectDoctorReport() doctorReport {
+	report := doctorReport{
+		Version: version,
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Env:     relevantEnv(),
+		// Keeps the JSON output a list when DuckDB cannot be asked
+		Extensions: []extensionStatus{},
+	}
+
+	path, err := exec.LookPath("duckdb")
+	if err != nil {
+		return report
+	}
+	report.DuckDBPath = path
+	if output, err := captureCommand([]string{"duckdb", "--version"}); err == nil {
+		report.DuckDBVersion = strings.TrimSpace(string(output))
+	}
+	if statuses, err := extensionStatuses(doctorExtensions); err == nil {
+		report.Extensions = statuses
+	} else {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return report
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func printDoctorReport(report doctorReport) {
+	duckdbPath := report.DuckDBPath
+	if duckdbPath == "" {
+		duckdbPath = "not found in PATH"
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "dpi version:\t%s\n", report.Version)
+	fmt.Fprintf(w, "duckdb path:\t%s\n", duckdbPath)
+	fmt.Fprintf(w, "duckdb version:\t%s\n", report.DuckDBVersion)
+	fmt.Fprintf(w, "platform:\t%s/%s\n", report.OS, report.Arch)
+	w.Flush()
+
+	fmt.Fprintln(os.Stdout, "\nEnvironment:")
+	if len(report.Env) == 0 {
+		fmt.Fprintln(os.Stdout, "  (none set)")
+	}
+	names := make([]string, 0, len(report.Env))
+	for name := range report.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stdout, "  %s=%s\n", name, report.Env[name])
+	}
+
+	fmt.Fprintln(os.Stdout, "\nExtensions:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tINSTALLED\tLOADED")
+	for _, e := range report.Extensions {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", e.Name, yesNo(e.Installed), yesNo(e.Loaded))
+	}
+	w.Flush()
+}
+
+func runDoctorCommand(cmd *cobra.Command, args []string) {
+	report := collectDoctorReport()
+	if cmd.Flag("json").Value.String() == "true" {
+		output, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		fmt.Println(string(output))
+		return
+	}
+	printDoctorReport(report)
+}
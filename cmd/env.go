@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const envPrefix = "DPI_"
+
+// envName returns the environment variable for a flag: DPI_ followed by the subcommand path and the
+// flag name in upper case with dashes replaced by underscores, e.g. DPI_ALL_VARCHAR or DPI_BENCH_RUNS.
+func envName(cmd *cobra.Command, flag string) string {
+	name := flag
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		name = c.Name() + "_" + name
+	}
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// environmentDefaults holds the built-in default of every flag set from the environment
+var environmentDefaults = map[*pflag.Flag]string{}
+
+// applyEnvironment makes the DPI_ environment variable of every flag of the command and its subcommands
+// the default of the flag. It runs before the command line is parsed, and the values are set without
+// marking the flags as changed, so they never conflict with the flags given as arguments and are
+// replaced by them, also for repeatable flags.
+func applyEnvironment(cmd *cobra.Command) error {
+	var err error
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Name == "help" || f.Name == "version" {
+			return
+		}
+		name := envName(cmd, f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		original := f.DefValue
+		var setErr error
+		// Replace keeps a repeatable flag's first argument from appending to the environment's value
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			setErr = slice.Replace([]string{value})
+		} else {
+			setErr = f.Value.Set(value)
+		}
+		if setErr != nil {
+			err = fmt.Errorf("invalid value for %s: %w", name, setErr)
+			return
+		}
+		f.DefValue = f.Value.String()
+		environmentDefaults[f] = original
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range cmd.Commands() {
+		if err := applyEnvironment(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fromEnvironment reports whether the flag has its value from the environment rather than the command line
+func fromEnvironment(cmd *cobra.Command, name string) bool {
+	f := cmd.Flags().Lookup(name)
+	if f == nil || f.Changed {
+		return false
+	}
+	_, ok := environmentDefaults[f]
+	return ok
+}
+
+// mutuallyExclusiveAnnotation is the flag annotation in which cobra records the groups of
+// MarkFlagsMutuallyExclusive, each as the space separated flag names
+const mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+
+// dropConflictingDefaults restores the built-in defaults of the flags set from the environment that
+// are mutually exclusive with a flag given as an argument, so that --run replaces DPI_EXEC or
+// --exclude-columns replaces DPI_COLUMNS instead of both being used
+func dropConflictingDefaults(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || !fromEnvironment(cmd, f.Name) {
+			return
+		}
+		for _, group := range f.Annotations[mutuallyExclusiveAnnotation] {
+			if !slices.ContainsFunc(strings.Fields(group), cmd.Flags().Changed) {
+				continue
+			}
+			if err = f.Value.Set(environmentDefaults[f]); err != nil {
+				return
+			}
+			f.DefValue = environmentDefaults[f]
+			delete(environmentDefaults, f)
+			return
+		}
+	})
+	return err
+}
+
+// flagGiven reports whether the flag was given as an argument or set in the environment
+func flagGiven(cmd *cobra.Command, name string) bool {
+	return cmd.Flags().Changed(name) || fromEnvironment(cmd, name)
+}
+
+// unusedDefault reports whether a flag set in the environment is left unused because it is not
+// supported here, e.g. DPI_DELIM for a Parquet file. The environment sets defaults for every run, while
+// a flag given as an argument where it is not supported is still an error.
+func unusedDefault(cmd *cobra.Command, name string, supported bool) bool {
+	return !supported && fromEnvironment(cmd, name)
+}
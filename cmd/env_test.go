@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// newEnvTestCommand returns a command with flags like those of dpi, named so its variables start with DPI_
+func newEnvTestCommand() *cobra.Command {
+	cmd := &cobra.Command{Use: "dpi"}
+	cmd.Flags().String("delim", "", "")
+	cmd.Flags().Int("parallel", 4, "")
+	cmd.Flags().String("columns", "", "")
+	cmd.Flags().String("exclude-columns", "", "")
+	cmd.Flags().StringArray("set", nil, "")
+	cmd.MarkFlagsMutuallyExclusive("columns", "exclude-columns")
+	return cmd
+}
+
+func TestApplyEnvironmentSetsDefaults(t *testing.T) {
+	t.Setenv("DPI_DELIM", ";")
+	t.Setenv("DPI_PARALLEL", "2")
+	cmd := newEnvTestCommand()
+	if err := applyEnvironment(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ParseFlags(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"delim": ";", "parallel": "2"} {
+		if got := cmd.Flag(name).Value.String(); got != want {
+			t.Errorf("--%s = %q, want %q", name, got, want)
+		}
+		if cmd.Flags().Changed(name) {
+			t.Errorf("--%s is marked as changed by the environment", name)
+		}
+		if !fromEnvironment(cmd, name) || !flagGiven(cmd, name) {
+			t.Errorf("--%s is not reported as set from the environment", name)
+		}
+	}
+}
+
+func TestApplyEnvironmentArgumentsWin(t *testing.T) {
+	t.Setenv("DPI_DELIM", ";")
+	t.Setenv("DPI_SET", "threads=3")
+	cmd := newEnvTestCommand()
+	if err := applyEnvironment(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ParseFlags([]string{"--delim", "|", "--set", "memory_limit=1GB"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cmd.Flag("delim").Value.String(); got != "|" {
+		t.Errorf("--delim = %q, want %q", got, "|")
+	}
+	set, err := cmd.Flags().GetStringArray("set")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 1 || set[0] != "memory_limit=1GB" {
+		t.Errorf("--set = %q, want only the value given as an argument", set)
+	}
+	if fromEnvironment(cmd, "delim") {
+		t.Error("--delim given as an argument is reported as set from the environment")
+	}
+}
+
+func TestApplyEnvironmentInvalidValue(t *testing.T) {
+	t.Setenv("DPI_PARALLEL", "many")
+	if err := applyEnvironment(newEnvTestCommand()); err == nil {
+		t.Error("expected an error for DPI_PARALLEL=many")
+	}
+}
+
+func TestDropConflictingDefaults(t *testing.T) {
+	t.Setenv("DPI_COLUMNS", "id")
+	cmd := newEnvTestCommand()
+	if err := applyEnvironment(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ParseFlags([]string{"--exclude-columns", "name"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dropConflictingDefaults(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ValidateFlagGroups(); err != nil {
+		t.Errorf("the environment default conflicts with the argument: %v", err)
+	}
+	if got := cmd.Flag("columns").Value.String(); got != "" {
+		t.Errorf("--columns = %q, want the environment value dropped", got)
+	}
+}
+
+// TestMutuallyExclusiveAnnotation guards the private annotation key of cobra read by
+// dropConflictingDefaults, which silently stops dropping conflicting defaults if cobra renames it
+func TestMutuallyExclusiveAnnotation(t *testing.T) {
+	cmd := newEnvTestCommand()
+	for _, name := range []string{"columns", "exclude-columns"} {
+		groups := cmd.Flags().Lookup(name).Annotations[mutuallyExclusiveAnnotation]
+		if len(groups) != 1 || groups[0] != "columns exclude-columns" {
+			t.Errorf("--%s has the groups %q under %s, want [\"columns exclude-columns\"]", name, groups, mutuallyExclusiveAnnotation)
+		}
+	}
+}
+
+func TestUnusedDefault(t *testing.T) {
+	t.Setenv("DPI_DELIM", ";")
+	cmd := newEnvTestCommand()
+	if err := applyEnvironment(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.ParseFlags([]string{"--columns", "id"}); err != nil {
+		t.Fatal(err)
+	}
+	if !unusedDefault(cmd, "delim", false) {
+		t.Error("DPI_DELIM should be unused where it is not supported")
+	}
+	if unusedDefault(cmd, "delim", true) {
+		t.Error("DPI_DELIM should be used where it is supported")
+	}
+	if unusedDefault(cmd, "columns", false) {
+		t.Error("--columns given as an argument must not be dropped, it is rejected where it is not supported")
+	}
+}
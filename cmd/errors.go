@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrorFormat is how dpi writes its error messages to stderr
+type ErrorFormat string
+
+const (
+	ErrorText ErrorFormat = "text"
+	ErrorJSON ErrorFormat = "json"
+)
+
+// errorFormat is set by the persistent --error-format flag, so every command reports its errors the same way
+var errorFormat = ErrorText
+
+// parseErrorFormat parses the --error-format flag
+func parseErrorFormat(s string) (ErrorFormat, error) {
+	switch f := ErrorFormat(strings.ToLower(s)); f {
+	case ErrorText, ErrorJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported --error-format '%s' (expected %s or %s)", s, ErrorText, ErrorJSON)
+	}
+}
+
+func (f *ErrorFormat) String() string { return string(*f) }
+
+func (f *ErrorFormat) Type() string { return "string" }
+
+func (f *ErrorFormat) Set(s string) error {
+	format, err := parseErrorFormat(s)
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+// applyErrorFormat silences cobra's own plain error and usage output with JSON errors, the errors it
+// returns are reported by Execute instead. It runs once the flags are parsed; flags given after a
+// malformed one are not parsed, so --error-format should come first.
+func applyErrorFormat(cmd *cobra.Command) {
+	silent := errorFormat == ErrorJSON
+	cmd.Root().SilenceErrors = silent
+	cmd.Root().SilenceUsage = silent
+}
+
+// formatEarlyErrors applies the error format to the flag and argument errors of cmd and its
+// subcommands, which cobra reports before any PersistentPreRunE runs
+func formatEarlyErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		applyErrorFormat(c)
+		return err
+	})
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			applyErrorFormat(c)
+			return validate(c, args)
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		formatEarlyErrors(sub)
+	}
+}
+
+// errorReport is the JSON object written for an error with --error-format json
+type errorReport struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+// reportError writes the message to stderr in the --error-format, with the exit code dpi is going to
+// exit with. It does not exit, so several errors can be reported before failing.
+func reportError(message string, code int) {
+	if errorFormat == ErrorJSON {
+		// Marshaling a string and an int cannot fail
+		data, _ := json.Marshal(errorReport{Error: message, Code: code})
+		fmt.Fprintln(os.Stderr, string(data))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %s\n", message)
+}
+
+func exitWithError(format string, args ...any) {
+	reportError(fmt.Sprintf(format, args...), 1)
+	os.Exit(1)
+}
+
+// exitWithCommandError reports the error and exits with the exit code of the failed command, or 1 when
+// the error did not come from a command that exited
+func exitWithCommandError(err error) {
+	code := commandExitCode(err)
+	reportError(err.Error(), code)
+	os.Exit(code)
+}
+
+// commandExitCode returns the exit code of the command the error came from, or 1 for other errors
+func commandExitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
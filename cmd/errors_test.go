@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// withErrorFormat sets the --error-format for the test
+func withErrorFormat(t *testing.T, format ErrorFormat) {
+	t.Helper()
+	saved := errorFormat
+	errorFormat = format
+	t.Cleanup(func() { errorFormat = saved })
+}
+
+func TestParseErrorFormat(t *testing.T) {
+	for in, want := range map[string]ErrorFormat{"text": ErrorText, "json": ErrorJSON, "JSON": ErrorJSON} {
+		if got, err := parseErrorFormat(in); err != nil || got != want {
+			t.Errorf("parseErrorFormat(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := parseErrorFormat("yaml"); err == nil {
+		t.Error("parseErrorFormat(yaml) succeeded")
+	}
+}
+
+func TestReportErrorJSON(t *testing.T) {
+	withErrorFormat(t, ErrorJSON)
+	_, stderr := captureOutput(t, func() {
+		reportError(`file "a.csv" not found`, 3)
+		reportError("second", 1)
+	})
+
+	lines := strings.Split(strings.TrimSuffix(stderr, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want one line per error, got %q", stderr)
+	}
+	var report map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &report); err != nil {
+		t.Fatalf("%q is not a JSON object: %v", lines[0], err)
+	}
+	want := map[string]any{"error": `file "a.csv" not found`, "code": float64(3)}
+	if fmt.Sprint(report) != fmt.Sprint(want) {
+		t.Errorf("report = %v, want %v", report, want)
+	}
+	if lines[1] != `{"error":"second","code":1}` {
+		t.Errorf("report = %s", lines[1])
+	}
+}
+
+func TestReportErrorText(t *testing.T) {
+	withErrorFormat(t, ErrorText)
+	_, stderr := captureOutput(t, func() { reportError("broken", 2) })
+	if stderr != "Error: broken\n" {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestCommandExitCode(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"plain error", errors.New("broken"), 1},
+		{"command exit code", exitErr, 3},
+		{"wrapped command exit code", fmt.Errorf("failed to execute query: %w", exitErr), 3},
+	}
+	for _, tt := range tests {
+		if got := commandExitCode(tt.err); got != tt.want {
+			t.Errorf("%s: commandExitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// newErrorTestCommand returns a command tree with the --error-format flag, whose subcommand fails
+func newErrorTestCommand() (*cobra.Command, *cobra.Command) {
+	root := &cobra.Command{Use: "dpi", Args: cobra.MinimumNArgs(1), Run: func(*cobra.Command, []string) {}}
+	root.PersistentFlags().Var(&errorFormat, "error-format", "")
+	sub := &cobra.Command{Use: "sub", RunE: func(*cobra.Command, []string) error { return errors.New("broken") }}
+	sub.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		applyErrorFormat(cmd)
+		return nil
+	}
+	root.AddCommand(sub)
+	formatEarlyErrors(root)
+	return root, sub
+}
+
+func TestErrorFormatSilencesCobra(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		silent bool
+	}{
+		{"text", []string{"sub"}, false},
+		{"json", []string{"sub", "--error-format", "json"}, true},
+		{"json with an unknown flag", []string{"--error-format", "json", "--nope"}, true},
+		{"json with missing arguments", []string{"--error-format", "json"}, true},
+		{"text with missing arguments", nil, false},
+	}
+	for _, tt := range tests {
+		withErrorFormat(t, ErrorText)
+		root, _ := newErrorTestCommand()
+		var out strings.Builder
+		root.SetOut(&out)
+		root.SetErr(&out)
+		root.SetArgs(tt.args)
+
+		if err := root.Execute(); err == nil {
+			t.Errorf("%s: the command did not fail", tt.name)
+		}
+		if root.SilenceErrors != tt.silent || root.SilenceUsage != tt.silent {
+			t.Errorf("%s: SilenceErrors = %v, SilenceUsage = %v, want %v", tt.name, root.SilenceErrors, root.SilenceUsage, tt.silent)
+		}
+		if tt.silent && out.Len() > 0 {
+			t.Errorf("%s: cobra printed %q", tt.name, out.String())
+		}
+		if !tt.silent && !strings.Contains(out.String(), "Error:") {
+			t.Errorf("%s: cobra did not print the error: %q", tt.name, out.String())
+		}
+	}
+}
+
+func TestErrorFormatSetOnlyParses(t *testing.T) {
+	withErrorFormat(t, ErrorText)
+	silenced := rootCmd.SilenceErrors
+	var f ErrorFormat
+	if err := f.Set("json"); err != nil || f != ErrorJSON {
+		t.Fatalf("Set(json) = %v, format %q", err, f)
+	}
+	if rootCmd.SilenceErrors != silenced {
+		t.Error("Set changed the root command")
+	}
+	if err := f.Set("xml"); err == nil {
+		t.Error("Set(xml) succeeded")
+	}
+}
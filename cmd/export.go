@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ExportFormat is the DuckDB COPY format used to write an output file
+type ExportFormat string
+
+const (
+	ExportParquet ExportFormat = "parquet"
+	ExportCSV     ExportFormat = "csv"
+	ExportJSON    ExportFormat = "json"
+)
+
+// exportFormats maps the extensions of output files to their format
+var exportFormats = map[string]ExportFormat{
+	".parquet": ExportParquet,
+	".csv":     ExportCSV,
+	".json":    ExportJSON,
+	".ndjson":  ExportJSON,
+	".jsonl":   ExportJSON,
+}
+
+// OnConflict is what the commands writing an output file do when it already exists
+type OnConflict string
+
+const (
+	OnConflictFail      OnConflict = "fail"
+	OnConflictOverwrite OnConflict = "overwrite"
+	OnConflictSkip      OnConflict = "skip"
+)
+
+// parseOnConflict parses the --on-conflict flag
+func parseOnConflict(s string) (OnConflict, error) {
+	switch c := OnConflict(strings.ToLower(s)); c {
+	case OnConflictFail, OnConflictOverwrite, OnConflictSkip:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unsupported --on-conflict '%s' (expected %s, %s or %s)", s, OnConflictFail, OnConflictOverwrite, OnConflictSkip)
+	}
+}
+
+// exportCompressions lists the codecs DuckDB can write for every output format. "none" is spelled
+// "uncompressed" for Parquet.
+var exportCompressions = map[ExportFormat][]string{
+	ExportParquet: {"snappy", "zstd", "gzip", "lz4", "brotli", "none"},
+	ExportCSV:     {"gzip", "zstd", "none"},
+	ExportJSON:    {"gzip", "zstd", "none"},
+}
+
+// exportFormatFor returns the format written to the output path, based on its extension. A .gz or .zst
+// suffix is ignored, DuckDB compresses such outputs by itself.
+func exportFormatFor(path string) (ExportFormat, error) {
+	name := path
+	if ext := compressionSuffix(name); ext == ".gz" || ext == ".zst" {
+		name = stripCompressionSuffix(name)
+	}
+	if format, ok := exportFormats[strings.ToLower(filepath.Ext(name))]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("cannot tell the output format of %s: use a .parquet, .csv, .json or .ndjson extension", path)
+}
+
+// exportSuffixes maps the codecs of CSV and JSON outputs to the suffix of the file name they are
+// written with
+var exportSuffixes = map[string]string{"gzip": ".gz", "zstd": ".zst", "none": ""}
+
+// checkExportCompression validates the --output-compression value for the output format. CSV and JSON
+// outputs are compressed as a whole, so the codec has to match the compression suffix of the path.
+func checkExportCompression(path string, format ExportFormat, compression string) error {
+	codecs := exportCompressions[format]
+	found := false
+	for _, codec := range codecs {
+		if compression == codec {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("unsupported --output-compression '%s' for %s output (expected one of %s)",
+			compression, format, strings.Join(codecs, ", "))
+	}
+	if format == ExportParquet {
+		return nil
+	}
+	if suffix := compressionSuffix(path); suffix != exportSuffixes[compression] {
+		if suffix == "" {
+			return fmt.Errorf("--output-compression '%s' needs a %s suffix on %s", compression, exportSuffixes[compression], path)
+		}
+		return fmt.Errorf("--output-compression '%s' does not match the %s suffix of %s", compression, suffix, path)
+	}
+	return nil
+}
+
+// copyStatement returns the COPY statement writing the result of the query to the output path.
+// An empty compression leaves the choice to DuckDB, which is snappy for Parquet and based on the
+// extension otherwise. quoteAll quotes every field of a CSV output.
+func copyStatement(query string, path string, format ExportFormat, compression string, quoteAll bool) string {
+	options := []string{"FORMAT " + string(format)}
+	if quoteAll {
+		options = append(options, "FORCE_QUOTE *")
+	}
+	if compression != "" {
+		if format == ExportParquet && compression == "none" {
+			compression = "uncompressed"
+		}
+		options = append(options, "COMPRESSION "+compression)
+	}
+	return fmt.Sprintf("COPY (%s) TO %s (%s);", query, quoteLiteral(path), strings.Join(options, ", "))
+}
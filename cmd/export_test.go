@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportFormatFor(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    ExportFormat
+		wantErr bool
+	}{
+		{"out.parquet", ExportParquet, false},
+		{"out.PARQUET", ExportParquet, false},
+		{"dir/out.csv", ExportCSV, false},
+		{"out.csv.gz", ExportCSV, false},
+		{"out.csv.zst", ExportCSV, false},
+		{"out.json", ExportJSON, false},
+		{"out.ndjson", ExportJSON, false},
+		{"out.jsonl.gz", ExportJSON, false},
+
+		{"out.txt", "", true},
+		{"out", "", true},
+		{"out.gz", "", true},
+		{"out.csv.bz2", "", true},
+		{"out.csv.zstd", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := exportFormatFor(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("exportFormatFor(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("exportFormatFor(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCheckExportCompression(t *testing.T) {
+	tests := []struct {
+		path        string
+		format      ExportFormat
+		compression string
+		wantErr     bool
+	}{
+		{"out.parquet", ExportParquet, "snappy", false},
+		{"out.parquet", ExportParquet, "zstd", false},
+		{"out.parquet", ExportParquet, "brotli", false},
+		{"out.parquet", ExportParquet, "none", false},
+		{"out.parquet", ExportParquet, "xz", true},
+		{"out.parquet", ExportParquet, "uncompressed", true},
+
+		{"out.csv", ExportCSV, "none", false},
+		{"out.csv.gz", ExportCSV, "gzip", false},
+		{"out.csv.zst", ExportCSV, "zstd", false},
+		{"out.ndjson.gz", ExportJSON, "gzip", false},
+		{"out.csv", ExportCSV, "snappy", true},
+		{"out.json", ExportJSON, "brotli", true},
+
+		// The codec has to match the suffix
+		{"out.csv.gz", ExportCSV, "zstd", true},
+		{"out.csv.zst", ExportCSV, "gzip", true},
+		{"out.csv.gz", ExportCSV, "none", true},
+		{"out.csv", ExportCSV, "gzip", true},
+		{"out.json", ExportJSON, "zstd", true},
+	}
+
+	for _, tt := range tests {
+		err := checkExportCompression(tt.path, tt.format, tt.compression)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkExportCompression(%q, %q, %q) error = %v, wantErr %v", tt.path, tt.format, tt.compression, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCopyStatement(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		format      ExportFormat
+		compression string
+		quoteAll    bool
+		want        string
+	}{
+		{"default compression", "out.parquet", ExportParquet, "", false,
+			"COPY (SELECT 1) TO 'out.parquet' (FORMAT parquet);"},
+		{"parquet codec", "out.parquet", ExportParquet, "zstd", false,
+			"COPY (SELECT 1) TO 'out.parquet' (FORMAT parquet, COMPRESSION zstd);"},
+		{"uncompressed parquet", "out.parquet", ExportParquet, "none", false,
+			"COPY (SELECT 1) TO 'out.parquet' (FORMAT parquet, COMPRESSION uncompressed);"},
+		{"uncompressed csv", "out.csv", ExportCSV, "none", false,
+			"COPY (SELECT 1) TO 'out.csv' (FORMAT csv, COMPRESSION none);"},
+		{"quote all", "out.csv.gz", ExportCSV, "gzip", true,
+			"COPY (SELECT 1) TO 'out.csv.gz' (FORMAT csv, FORCE_QUOTE *, COMPRESSION gzip);"},
+		{"json", "out.ndjson", ExportJSON, "", false,
+			"COPY (SELECT 1) TO 'out.ndjson' (FORMAT json);"},
+		{"quoted path", "it's.csv", ExportCSV, "", false,
+			"COPY (SELECT 1) TO 'it''s.csv' (FORMAT csv);"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := copyStatement("SELECT 1", tt.path, tt.format, tt.compression, tt.quoteAll); got != tt.want {
+				t.Errorf("copyStatement() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOnConflict(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    OnConflict
+		wantErr bool
+	}{
+		{"fail", OnConflictFail, false},
+		{"overwrite", OnConflictOverwrite, false},
+		{"skip", OnConflictSkip, false},
+		{"Overwrite", OnConflictOverwrite, false},
+		{"replace", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOnConflict(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOnConflict(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseOnConflict(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCheckNotInput(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTestFile(t, dir, "data.csv", "a\n1\n")
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relative, err := filepath.Rel(cwd, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		output  string
+		wantErr bool
+	}{
+		{input, true},
+		{relative, true},
+		{filepath.Join(dir, "sub", "..", "data.csv"), true},
+		{filepath.Join(dir, "data.parquet"), false},
+		{filepath.Join(dir, "data.csv.gz"), false},
+	}
+
+	for _, tt := range tests {
+		err := checkNotInput(tt.output, []string{filepath.Join(dir, "other.csv"), input})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkNotInput(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// requiredExtension returns the DuckDB extension needed to read the format, if any
+func requiredExtension(fileFormat FileFormat) string {
+	switch fileFormat {
+	case Delta:
+		return "delta"
+	case Iceberg:
+		return "iceberg"
+	case Arrow:
+		return "nanoarrow"
+	default:
+		return ""
+	}
+}
+
+// communityExtensions are the extensions dpi uses that are published in the community repository
+// rather than DuckDB's core one
+var communityExtensions = map[string]bool{
+	"prql":      true,
+	"nanoarrow": true,
+}
+
+// installStatement returns the INSTALL statement of the extension, naming its repository
+func installStatement(name string) string {
+	if communityExtensions[name] {
+		return fmt.Sprintf("INSTALL %s FROM community;", name)
+	}
+	return fmt.Sprintf("INSTALL %s;", name)
+}
+
+// noAutoloadSettings keep DuckDB from installing and loading the extensions it knows about when a
+// query needs them, so nothing is downloaded behind the user's back
+const noAutoloadSettings = "SET autoinstall_known_extensions = false; SET autoload_known_extensions = false; "
+
+// ensureExtension installs and loads the extension once up front, so a missing extension is
+// reported clearly instead of failing in the middle of creating the table. Without install, the
+// extension is only loaded and must have been installed before.
+func ensureExtension(name string, install bool) error {
+	if !install {
+		cmds := []string{"duckdb", "-c", fmt.Sprintf("LOAD %s;", name)}
+		if _, err := captureCommand(cmds); err != nil {
+			return fmt.Errorf("failed to load the DuckDB '%s' extension: %w\n"+
+				"Extensions are not installed with --no-autoload-known-extensions, install it beforehand with: duckdb -c \"%s\"",
+				name, err, installStatement(name))
+		}
+		return nil
+	}
+
+	statement := installStatement(name)
+	cmds := []string{"duckdb", "-c", fmt.Sprintf("%s LOAD %s;", statement, name)}
+	if _, err := captureCommand(cmds); err != nil {
+		return fmt.Errorf("failed to install the DuckDB '%s' extension: %w\n"+
+			"The extension is downloaded on first use, so check your network connection or install it "+
+			"manually with: duckdb -c \"%s\"", name, err, statement)
+	}
+	return nil
+}
+
+// isDeltaTable reports whether the path is a Delta Lake table directory, i.e. it contains a _delta_log
+func isDeltaTable(path string) bool {
+	info, err := os.Stat(filepath.Join(path, "_delta_log"))
+	return err == nil && info.IsDir()
+}
+
+// isIcebergTable reports whether the path is an Iceberg table directory, i.e. its metadata
+// directory holds at least one *.metadata.json file
+func isIcebergTable(path string) bool {
+	matches, err := filepath.Glob(filepath.Join(path, "metadata", "*.metadata.json"))
+	return err == nil && len(matches) > 0
+}
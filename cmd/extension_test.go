@@ -0,0 +1,11 @@
+package cmd
+
+import "testing"
+
+func TestRequiredExtension(t *testing.T) {
+	for format, want := range map[FileFormat]string{Delta: "delta", Iceberg: "iceberg", Arrow: "nanoarrow", Parquet: "", CSV: "", JSON: ""} {
+		if got := requiredExtension(format); got != want {
+			t.Errorf("requiredExtension(%s) = %q, want %q", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// extensionUses describes what dpi needs each extension for, so a failing format can be traced to it
+var extensionUses = map[string]string{
+	"parquet":   "Parquet files",
+	"json":      "JSON files and output",
+	"httpfs":    "remote files (s3://, https://, ...)",
+	"delta":     "Delta Lake tables",
+	"iceberg":   "Iceberg tables",
+	"prql":      "--dialect prql queries",
+	"nanoarrow": "Arrow IPC files",
+}
+
+var extensionsCmd = &cobra.Command{
+	Use:   "extensions",
+	Short: "List the DuckDB extensions and whether they are installed and loaded",
+	Long: `List the extensions DuckDB knows about with their install and load state, and which of them dpi
+uses for which inputs. Extensions are installed on first use, so a format that fails to load is often
+explained by its extension missing here.`,
+	Example: `  dpi extensions
+  dpi extensions --installed`,
+	Args: cobra.NoArgs,
+	Run:  runExtensionsCommand,
+}
+
+func init() {
+	extensionsCmd.Flags().Bool("installed", false, "Only list installed extensions")
+	rootCmd.AddCommand(extensionsCmd)
+}
+
+func runExtensionsCommand(cmd *cobra.Command, args []string) {
+	statuses, err := extensionStatuses(nil)
+	if err != nil {
+		exitWithError("%v", err)
+	}
+	installedOnly := cmd.Flag("installed").Value.String() == "true"
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tINSTALLED\tLOADED\tVERSION\tUSED BY DPI FOR\tDESCRIPTION")
+	for _, e := range statuses {
+		if installedOnly && !e.Installed {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Name, yesNo(e.Installed), yesNo(e.Loaded), e.Version, extensionUses[e.Name], e.Description)
+	}
+	w.Flush()
+}
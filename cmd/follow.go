@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// followInterval is how often --follow checks the file for appended rows
+const followInterval = time.Second
+
+// writeChunk writes the header and the bytes of the file from offset up to size to chunk, ending
+// with the last complete line, so a row that is still being written is left for the next poll. The
+// range is streamed, a large append is not held in memory. It returns the number of bytes and of
+// lines taken from the file, both 0 when the range holds no complete line.
+func writeChunk(chunk string, header []byte, path string, offset int64, size int64) (int64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	out, err := os.OpenFile(chunk, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to write the new rows: %w", err)
+	}
+	defer out.Close()
+	if _, err := out.Write(header); err != nil {
+		return 0, 0, fmt.Errorf("failed to write the new rows: %w", err)
+	}
+	w := &lineEndWriter{w: out, last: -1}
+	if _, err := io.CopyBuffer(w, io.NewSectionReader(f, offset, size-offset), make([]byte, 1<<20)); err != nil {
+		return 0, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if w.last < 0 {
+		return 0, 0, nil
+	}
+	if err := out.Truncate(int64(len(header)) + w.last + 1); err != nil {
+		return 0, 0, fmt.Errorf("failed to write the new rows: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return 0, 0, fmt.Errorf("failed to write the new rows: %w", err)
+	}
+	return w.last + 1, w.lines, nil
+}
+
+// csvHeader returns the first line of the file including its line break, the number of complete
+// lines after it and the offset just past the last of them
+func csvHeader(path string) ([]byte, int64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("%s has no complete header line", path)
+	}
+	var rows int64
+	pos, end := int64(len(header)), int64(len(header))
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(buf)
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			rows += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			end = pos + int64(i) + 1
+		}
+		pos += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	return header, rows, end, nil
+}
+
+// followCSV prints the rows appended to a growing CSV file until dpi is interrupted, like tail -f. Every
+// batch of new lines is written to a copy below the header and read with the same options as the file,
+// so the rows are printed with their columns. A file that shrinks was truncated or replaced and is
+// followed again from its first row.
+func followCSV(input inputTable, tempDir string, outputArgs []string) error {
+	if input.fileFormat != CSV || input.opts.remote || isCompressed(input.files[0]) || len(input.files) != 1 {
+		return fmt.Errorf("--follow is only supported for a single local uncompressed CSV file")
+	}
+	path := input.files[0]
+	// Start after the last complete line, a partial one is printed once it is finished
+	header, rows, offset, err := csvHeader(path)
+	if err != nil {
+		return err
+	}
+	// A few new rows would often be detected with other types than the file, like t as a BOOLEAN
+	if !input.opts.allVarchar && len(input.opts.fastColumns) == 0 {
+		read := tableOptions{strict: input.opts.strict, delim: input.opts.delim, dateFormat: input.opts.dateFormat,
+			timestampFormat: input.opts.timestampFormat, maxLineSize: input.opts.maxLineSize}
+		query, err := buildSelectQuery(toFileNameString([]string{path}), CSV, read)
+		if err != nil {
+			return err
+		}
+		if input.opts.columnTypes, err = describeQuery(sessionSetup(CSV, input.opts), query); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Following %s from row %d, press Ctrl-C to stop\n", input.path, rows+1)
+
+	chunk := filepath.Join(tempDir, "follow_"+filepath.Base(path))
+
+	for !signalReceived.Load() {
+		time.Sleep(followInterval)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.Size() < offset {
+			fmt.Fprintf(os.Stderr, "%s was truncated, following it from the first row\n", input.path)
+			offset, rows = int64(len(header)), 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		n, added, err := writeChunk(chunk, header, path, offset, info.Size())
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+		// Options like --columns read the schema of the copy, so the query is built once it exists
+		query, err := buildSelectQuery(toFileNameString([]string{chunk}), CSV, input.opts)
+		if err != nil {
+			return err
+		}
+		statement := sessionSetup(CSV, input.opts) + query + ";"
+		cmds := append([]string{"duckdb"}, outputArgs...)
+		if err := executeCommand(append(cmds, "-c", statement)); err != nil && !signalReceived.Load() {
+			return fmt.Errorf("failed to read the new rows: %w", err)
+		}
+		offset += n
+		rows += added
+		fmt.Fprintf(os.Stderr, "%d new row(s), %d in total\n", added, rows)
+	}
+	return nil
+}
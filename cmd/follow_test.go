@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func appendTestFile(t *testing.T, path string, contents string) int64 {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatal(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestCSVHeader(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		contents string
+		header   string
+		rows     int64
+		offset   int64
+	}{
+		{"header only", "id,name\n", "id,name\n", 0, 8},
+		{"complete rows", "id,name\n1,a\n2,b\n", "id,name\n", 2, 16},
+		{"partial last line", "id,name\n1,a\n2,b", "id,name\n", 1, 12},
+		{"CRLF", "id,name\r\n1,a\r\n", "id,name\r\n", 1, 14},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, dir, tt.name+".csv", tt.contents)
+			header, rows, offset, err := csvHeader(path)
+			if err != nil {
+				t.Fatalf("csvHeader() error = %v", err)
+			}
+			if string(header) != tt.header || rows != tt.rows || offset != tt.offset {
+				t.Errorf("csvHeader() = %q, %d, %d, want %q, %d, %d", header, rows, offset, tt.header, tt.rows, tt.offset)
+			}
+		})
+	}
+
+	t.Run("no complete header", func(t *testing.T) {
+		path := writeTestFile(t, dir, "partial.csv", "id,na")
+		if _, _, _, err := csvHeader(path); err == nil {
+			t.Error("csvHeader() error = nil, want an error")
+		}
+	})
+
+	t.Run("rows spanning reads", func(t *testing.T) {
+		var b strings.Builder
+		b.WriteString("id,name\n")
+		for i := 0; i < 20000; i++ {
+			fmt.Fprintf(&b, "%d,name_%d\n", i, i)
+		}
+		b.WriteString("20000,partial")
+		path := writeTestFile(t, dir, "large.csv", b.String())
+		_, rows, offset, err := csvHeader(path)
+		if err != nil {
+			t.Fatalf("csvHeader() error = %v", err)
+		}
+		if want := int64(b.Len() - len("20000,partial")); rows != 20000 || offset != want {
+			t.Errorf("csvHeader() = %d rows up to %d, want 20000 up to %d", rows, offset, want)
+		}
+	})
+}
+
+// TestWriteChunk follows a file through several appends the way followCSV does, each poll taking the
+// complete lines after the offset
+func TestWriteChunk(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "log.csv", "id,name\n1,a\n2,")
+	chunk := filepath.Join(dir, "chunk.csv")
+
+	header, rows, offset, err := csvHeader(path)
+	if err != nil {
+		t.Fatalf("csvHeader() error = %v", err)
+	}
+
+	polls := []struct {
+		appended string
+		chunk    string // contents of the chunk, empty when there is no complete line
+		rows     int64
+	}{
+		{"b\n3,c\n4,", "id,name\n2,b\n3,c\n", 2},
+		{"", "", 0},
+		{"d", "", 0},
+		{"\n", "id,name\n4,d\n", 1},
+		{"5,e\n6,f\n7,g\n8", "id,name\n5,e\n6,f\n7,g\n", 3},
+	}
+
+	total := rows
+	for i, p := range polls {
+		size := appendTestFile(t, path, p.appended)
+		n, added, err := writeChunk(chunk, header, path, offset, size)
+		if err != nil {
+			t.Fatalf("poll %d: writeChunk() error = %v", i, err)
+		}
+		if added != p.rows {
+			t.Errorf("poll %d: writeChunk() added %d row(s), want %d", i, added, p.rows)
+		}
+		if p.chunk == "" {
+			if n != 0 {
+				t.Errorf("poll %d: writeChunk() took %d byte(s) without a complete line", i, n)
+			}
+			continue
+		}
+		got, err := os.ReadFile(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != p.chunk {
+			t.Errorf("poll %d: chunk = %q, want %q", i, got, p.chunk)
+		}
+		if want := int64(len(p.chunk) - len(header)); n != want {
+			t.Errorf("poll %d: writeChunk() took %d byte(s), want %d", i, n, want)
+		}
+		offset += n
+		total += added
+	}
+
+	if total != 7 {
+		t.Errorf("followed %d row(s), want 7", total)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest := string(contents[offset:]); rest != "8" {
+		t.Errorf("left %q for the next poll, want the partial line %q", rest, "8")
+	}
+
+	// A truncated file is followed again from its first row
+	if err := os.WriteFile(path, []byte("id,name\n9,i\n1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	n, added, err := writeChunk(chunk, header, path, int64(len(header)), 13)
+	if err != nil {
+		t.Fatalf("writeChunk() after truncation error = %v", err)
+	}
+	got, err := os.ReadFile(chunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 || added != 1 || string(got) != "id,name\n9,i\n" {
+		t.Errorf("writeChunk() after truncation = %d, %d with chunk %q, want 4, 1 with %q", n, added, got, "id,name\n9,i\n")
+	}
+}
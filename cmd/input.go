@@ -0,0 +1,912 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// limitFileBytes copies at most limit bytes of the file into tempDir, cutting the copy after the last
+// complete line so no partial row is read. It returns the path of the copy, or the original path when
+// the file is already smaller than the limit.
+func limitFileBytes(path string, tempDir string, limit int64) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() <= limit {
+		return path, nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst := filepath.Join(tempDir, "limited_"+filepath.Base(path))
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to write truncated copy: %w", err)
+	}
+	defer out.Close()
+
+	// The copy is streamed and cut after its last complete line once the limit is reached
+	w := &lineEndWriter{w: out, last: -1}
+	if _, err := io.CopyBuffer(w, io.LimitReader(src, limit), make([]byte, 1<<20)); err != nil {
+		return "", fmt.Errorf("failed to write truncated copy of %s: %w", path, err)
+	}
+	if w.last < 0 {
+		return "", fmt.Errorf("--limit-bytes %s is smaller than the first line of %s", formatBytes(limit), path)
+	}
+	if err := out.Truncate(w.last + 1); err != nil {
+		return "", fmt.Errorf("failed to write truncated copy: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to write truncated copy: %w", err)
+	}
+	return dst, nil
+}
+
+// lineEndWriter passes the bytes on to w and records the offset of the last line break written
+type lineEndWriter struct {
+	w     io.Writer
+	n     int64 // bytes written so far
+	last  int64 // offset of the last '\n', -1 before the first
+	lines int64 // line breaks written so far
+}
+
+func (l *lineEndWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	if i := bytes.LastIndexByte(p[:n], '\n'); i >= 0 {
+		l.last = l.n + int64(i)
+		l.lines += int64(bytes.Count(p[:n], []byte{'\n'}))
+	}
+	l.n += int64(n)
+	return n, err
+}
+
+// defaultCSVSampleSize is the number of rows DuckDB reads to detect the types of a CSV
+const defaultCSVSampleSize = 20480
+
+// csvSampleBudget bounds the bytes of the rows DuckDB samples for type detection. The whole sample is
+// held in memory, so very long lines are sampled from fewer rows.
+const csvSampleBudget = 256 << 20
+
+// boundedSampleSize estimates the line length of a large CSV from its first megabyte and returns the
+// sample size keeping the detection within csvSampleBudget with that length, or 0 when DuckDB's default
+// already does
+func boundedSampleSize(path string) (int64, int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if info.Size() <= csvSampleBudget {
+		return 0, 0, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 1<<20)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	lineSize := int64(n)
+	if lines := int64(bytes.Count(buf[:n], []byte{'\n'})); lines > 0 {
+		lineSize /= lines
+	}
+	rows := csvSampleBudget / max(lineSize, 1)
+	if rows >= defaultCSVSampleSize {
+		return 0, 0, nil
+	}
+	return max(rows, 1), lineSize, nil
+}
+
+// isCompressed reports whether the file name has a compression suffix
+func isCompressed(path string) bool {
+	return compressionSuffix(path) != ""
+}
+
+// utf8BOM is the byte order mark some tools, notably Excel, write at the start of UTF-8 files
+const utf8BOM = "\xef\xbb\xbf"
+
+// skipBOM returns the reader without a leading UTF-8 byte order mark. DuckDB skips it when reading
+// a CSV, so the header read here must too or the first column name would start with it.
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if prefix, err := br.Peek(len(utf8BOM)); err == nil && string(prefix) == utf8BOM {
+		br.Discard(len(utf8BOM))
+	}
+	return br
+}
+
+// openText opens the file for reading, decompressing gzip files on the fly and skipping a byte order mark
+func openText(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader = f
+	switch compressionSuffix(path) {
+	case "":
+	case ".gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+		}
+		r = gz
+	default:
+		f.Close()
+		return nil, fmt.Errorf("cannot read %s: only gzip compression is supported", path)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{skipBOM(r), f}, nil
+}
+
+// readCSVHeader returns the column names from the first line of a delimited file
+func readCSVHeader(path string, delim string) ([]string, error) {
+	f, err := openText(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.LazyQuotes = true
+	if delim != "" {
+		r := []rune(delim)
+		if len(r) != 1 {
+			return nil, fmt.Errorf("--fast needs a single character delimiter, got '%s'", delim)
+		}
+		reader.Comma = r[0]
+	}
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header of %s: %w", path, err)
+	}
+	return header, nil
+}
+
+// possibleDelimiters are the characters DuckDB's sniffer tries as CSV delimiters
+const possibleDelimiters = ",;\t|"
+
+// checkSingleColumnHeader prints a note when DuckDB takes the first line of a single column of text
+// for a header. With a single text column a header and a value look the same to its sniffer, which
+// only tells them apart by their types, so a list of words loses its first word to the column name.
+func checkSingleColumnHeader(input inputTable) error {
+	f, err := openText(input.files[0])
+	if err != nil {
+		// Formats dpi cannot decompress are only read by DuckDB
+		return nil
+	}
+	line, err := bufio.NewReader(f).ReadString('\n')
+	f.Close()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read the first line of %s: %w", input.files[0], err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	delims := possibleDelimiters
+	if input.opts.delim != "" {
+		delims = input.opts.delim
+	}
+	if line == "" || strings.ContainsAny(line, delims) {
+		return nil
+	}
+
+	columns, err := describeQuery(sessionSetup(CSV, input.opts), fmt.Sprintf("SELECT * FROM %s",
+		readFunction(toFileNameString(input.files[:1]), CSV, csvReadParams(input.opts))))
+	if err != nil {
+		return err
+	}
+	if len(columns) == 1 && columns[0].Type == "VARCHAR" && columns[0].Name == strings.Trim(line, `"`) {
+		fmt.Fprintf(statusOut, "Note: the first line of %s, '%s', is read as the name of its only column; pass --no-header if it is a value\n",
+			input.path, columns[0].Name)
+	}
+	return nil
+}
+
+// ambiguousCSVExtensions are the extensions of delimited files that are often not comma separated
+var ambiguousCSVExtensions = map[string]bool{".txt": true}
+
+// sniffDelimiter returns a tab delimiter when the first line of a file with an ambiguous extension
+// contains tabs but no commas, and "" to leave the detection to DuckDB
+func sniffDelimiter(path string) (string, error) {
+	if !ambiguousCSVExtensions[strings.ToLower(filepath.Ext(stripCompressionSuffix(path)))] {
+		return "", nil
+	}
+	// Only gzip can be decompressed here, DuckDB detects the delimiter of other files by itself
+	if suffix := compressionSuffix(path); suffix != "" && suffix != ".gz" {
+		return "", nil
+	}
+
+	f, err := openText(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read the first line of %s: %w", path, err)
+	}
+	if strings.Contains(line, "\t") && !strings.Contains(line, ",") {
+		return "\t", nil
+	}
+	return "", nil
+}
+
+// parseDelimiter accepts the delimiter as given or spelled as \t or tab
+func parseDelimiter(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return "", fmt.Errorf("--delim must not be empty")
+	case `\t`, "tab":
+		return "\t", nil
+	default:
+		return s, nil
+	}
+}
+
+// varcharColumnsStruct builds the read_csv columns parameter declaring every column as VARCHAR
+func varcharColumnsStruct(names []string) string {
+	items := make([]string, 0, len(names))
+	for _, name := range names {
+		items = append(items, quoteLiteral(name)+": 'VARCHAR'")
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// columnTypesStruct builds the read_csv types parameter giving every column its type
+func columnTypesStruct(columns []column) string {
+	items := make([]string, 0, len(columns))
+	for _, c := range columns {
+		items = append(items, quoteLiteral(c.Name)+": "+quoteLiteral(c.Type))
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// inputTable is one input argument resolved to its format and files, ready to be loaded into a table
+type inputTable struct {
+	name       string // table name, TableName unless several inputs are given
+	path       string // the argument as given by the user
+	fileFormat FileFormat
+	files      []string
+	opts       tableOptions
+}
+
+func (in inputTable) filename() FileNameString {
+	return toFileNameString(in.files)
+}
+
+// addReadFlags registers the flags that affect how the input files are read, shared by every command
+// that loads files through prepareInput
+func addReadFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("strict", "s", false, "Enable strict mode: strict CSV parsing, or for Parquet require all files to have the same schema")
+	cmd.Flags().BoolP("all-varchar", "a", false, "Read all columns as VARCHAR (disable type detection)")
+	cmd.Flags().BoolP("lowercase-columns", "l", false, "Alias all column names to their lowercase form")
+	cmd.Flags().String("columns", "", "Only load the given comma separated columns, in that order")
+	cmd.Flags().String("columns-file", "", "Only load the columns listed in the file, one per line, in that order")
+	cmd.Flags().String("exclude-columns", "", "Load every column except the given comma separated ones")
+	cmd.Flags().Int("limit-columns", 0, "Only load the first N columns, for a readable look at very wide files")
+	cmd.Flags().String("rename-map", "", "CSV file of old,new column names to rename, other columns keep their names")
+	cmd.Flags().Bool("union-by-name", false, "Match the columns of the files by name, filling columns missing from a file with NULL")
+	cmd.Flags().Int("limit-per-file", 0, "Only load N rows of every matched Parquet or CSV file, for a balanced preview")
+	cmd.Flags().String("sample", "", "Only load a random sample of N rows, or of a percentage of the rows like 10%")
+	cmd.Flags().String("sample-seed", "", "Seed between -1 and 1 making --sample pick the same rows, or --random-file the same file, on every run")
+	cmd.Flags().Bool("random-file", false, "Only load one file picked at random from those matched by the pattern, for a quick spot check")
+	cmd.Flags().Bool("with-filename", false, "Add a filename column with the file each row was read from")
+	cmd.Flags().Bool("relative-paths", false, "With --with-filename, show the file names relative to their common directory")
+	cmd.Flags().Bool("rownum", false, "Add a row number column rn as the first column of the table")
+	cmd.Flags().String("where", "", "Only load the rows matching the SQL condition on the columns of the files, e.g. 'amount > 100'")
+	cmd.Flags().String("partition-filter", "", "Only read the Hive partitions matching key=value[,key=value...]")
+	cmd.Flags().String("version-as-of", "", "Read the given version of a Delta table (time travel)")
+	cmd.Flags().String("snapshot", "", "Read the given snapshot id of an Iceberg table (time travel)")
+	cmd.Flags().String("as-of", "", "Read a Delta or Iceberg table as of a version or snapshot id, or an Iceberg table as of a timestamp")
+	cmd.Flags().String("limit-bytes", "", "Only read the first part of a CSV file, e.g. 100MB (cut at a line boundary)")
+	cmd.Flags().String("range", "", "Read the Parquet files numbered start-end by replacing {} in the path, e.g. 00000-00009")
+	cmd.Flags().String("temp-dir", "", "Directory for the temporary database and file copies (default: $TMPDIR, then the current directory)")
+	cmd.Flags().Bool("no-glob", false, "Treat the arguments as literal file names, e.g. for names containing [ or {")
+	cmd.Flags().String("member", "", "Only load the members of a tar archive matching the pattern, e.g. 'data/*.parquet'")
+	cmd.Flags().String("format", "", "Read the input as parquet, csv, json, arrow, delta or iceberg instead of detecting the format")
+	cmd.Flags().String("delim", "", "CSV delimiter, e.g. ';' or '\\t' (detected by DuckDB by default)")
+	cmd.Flags().String("date-format", "", "Format of the dates in a CSV, e.g. '%d/%m/%Y'")
+	cmd.Flags().String("timestamp-format", "", "Format of the timestamps in a CSV, e.g. '%d/%m/%Y %H:%M'")
+	cmd.Flags().String("max-line-size", "", "Longest line accepted in a CSV, e.g. 64MB, for rows with very long values")
+	cmd.Flags().Bool("no-header", false, "Read the first line of a CSV as data instead of letting DuckDB detect a header")
+	cmd.Flags().Int64("sample-size", 0, "Rows of a CSV DuckDB reads to detect the column types, -1 for all (default: 20480, fewer for very long lines)")
+	cmd.Flags().Bool("deterministic", false, "Load the rows in the same order on every run, using a single thread (slower)")
+	cmd.Flags().Bool("flatten", false, "Expand the fields of nested JSON objects into top-level columns named parent.field")
+	cmd.Flags().Int("flatten-depth", 3, "Levels of nested objects --flatten expands, deeper ones stay STRUCT columns")
+	cmd.Flags().Bool("no-autoload-known-extensions", false, "Never let DuckDB download or load extensions by itself, for airgapped machines")
+	cmd.Flags().StringArray("set", nil, "Set a DuckDB setting as key=value for the load and the session, e.g. memory_limit=4GB (repeatable)")
+	cmd.Flags().Bool("utc", false, "Use the UTC time zone for TIMESTAMPTZ values instead of the local one")
+	cmd.Flags().Bool("trim", false, "Strip leading and trailing spaces from the text columns of a CSV")
+	cmd.Flags().Bool("fast", false, "Load a CSV as quickly as possible: no type or dialect detection, all columns VARCHAR")
+	addS3Flags(cmd)
+	cmd.MarkFlagsMutuallyExclusive("range", "no-glob")
+	cmd.MarkFlagsMutuallyExclusive("columns", "limit-columns", "columns-file", "exclude-columns")
+	cmd.MarkFlagsMutuallyExclusive("as-of", "version-as-of")
+	cmd.MarkFlagsMutuallyExclusive("as-of", "snapshot")
+}
+
+// readOptions builds the table options from the read flags of the command
+func readOptions(cmd *cobra.Command) (tableOptions, error) {
+	opts := tableOptions{
+		strict:           cmd.Flag("strict").Value.String() == "true",
+		allVarchar:       cmd.Flag("all-varchar").Value.String() == "true",
+		lowercaseColumns: cmd.Flag("lowercase-columns").Value.String() == "true",
+		withFilename:     cmd.Flag("with-filename").Value.String() == "true",
+		rownum:           cmd.Flag("rownum").Value.String() == "true",
+		deterministic:    cmd.Flag("deterministic").Value.String() == "true",
+		unionByName:      cmd.Flag("union-by-name").Value.String() == "true",
+		randomFile:       cmd.Flag("random-file").Value.String() == "true",
+		noAutoload:       cmd.Flag("no-autoload-known-extensions").Value.String() == "true",
+		utc:              cmd.Flag("utc").Value.String() == "true",
+	}
+	limitPerFile, err := cmd.Flags().GetInt("limit-per-file")
+	if err != nil || limitPerFile < 0 {
+		return opts, fmt.Errorf("--limit-per-file must be a positive number")
+	}
+	opts.limitPerFile = limitPerFile
+	limitColumns, err := cmd.Flags().GetInt("limit-columns")
+	if err != nil || limitColumns < 0 {
+		return opts, fmt.Errorf("--limit-columns must be a positive number")
+	}
+	opts.limitColumns = limitColumns
+	if list := cmd.Flag("columns").Value.String(); list != "" {
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				opts.columns = append(opts.columns, name)
+			}
+		}
+	}
+	if list := cmd.Flag("exclude-columns").Value.String(); list != "" {
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				opts.excludeColumns = append(opts.excludeColumns, name)
+			}
+		}
+	}
+	if path := cmd.Flag("columns-file").Value.String(); path != "" {
+		if opts.columns, err = readColumnsFile(path); err != nil {
+			return opts, err
+		}
+	}
+	opts.where = strings.TrimSpace(cmd.Flag("where").Value.String())
+	pairs, err := cmd.Flags().GetStringArray("set")
+	if err != nil {
+		return opts, err
+	}
+	if opts.settings, err = parseSettings(pairs); err != nil {
+		return opts, err
+	}
+	if spec := cmd.Flag("sample").Value.String(); spec != "" {
+		if opts.sample, err = parseSample(spec); err != nil {
+			return opts, err
+		}
+	}
+	if seed := cmd.Flag("sample-seed").Value.String(); seed != "" && !unusedDefault(cmd, "sample-seed", opts.sample != "" || opts.randomFile) {
+		if opts.sample == "" && !opts.randomFile {
+			return opts, fmt.Errorf("--sample-seed requires --sample or --random-file")
+		}
+		if opts.sampleSeed, err = parseSampleSeed(seed); err != nil {
+			return opts, err
+		}
+	}
+	if opts.s3, err = readS3Config(cmd); err != nil {
+		return opts, err
+	}
+	if path := cmd.Flag("rename-map").Value.String(); path != "" {
+		if opts.renames, err = readRenameMap(path); err != nil {
+			return opts, err
+		}
+	}
+	if cmd.Flag("relative-paths").Value.String() == "true" && !opts.withFilename && !fromEnvironment(cmd, "relative-paths") {
+		return opts, fmt.Errorf("--relative-paths requires --with-filename")
+	}
+	return opts, nil
+}
+
+// prepareInput determines the format and files of one input argument and applies the flags that
+// depend on them. Files that have to be rewritten before reading are placed in tempDir.
+func prepareInput(cmd *cobra.Command, filePath string, tempDir string, opts tableOptions) (inputTable, error) {
+	input := inputTable{name: TableName, path: filePath, opts: opts}
+	input.opts.remote = isRemotePath(filePath)
+	if isTarArchive(filePath) && !input.opts.remote {
+		return prepareTarInput(cmd, input, tempDir)
+	}
+	if cmd.Flags().Changed("member") {
+		return input, fmt.Errorf("--member is only supported for tar archives")
+	}
+
+	// Determine file format
+	if format := cmd.Flag("format").Value.String(); format != "" {
+		fileFormat, err := parseFileFormat(format)
+		if err != nil {
+			return input, err
+		}
+		input.fileFormat = fileFormat
+	} else {
+		input.fileFormat = determineFileFormat(filePath)
+	}
+	if input.fileFormat == "" {
+		return input, fmt.Errorf("unsupported file format for file: %s", filePath)
+	}
+	fmt.Fprintf(statusOut, "Detected file format: %s (%s)\n", input.fileFormat, filePath)
+
+	// Process files based on format
+	var files []string
+	var err error
+	if spec := cmd.Flag("range").Value.String(); spec != "" && !unusedDefault(cmd, "range", input.fileFormat == Parquet) {
+		if input.fileFormat != Parquet {
+			return input, fmt.Errorf("--range is only supported for Parquet files")
+		}
+		files, err = expandRange(filePath, spec)
+	} else {
+		files, err = processInputFiles(filePath, input.fileFormat, tempDir, cmd.Flag("no-glob").Value.String() == "true")
+	}
+	if err != nil {
+		return input, err
+	}
+	input.files = files
+	return configureInput(cmd, input, tempDir)
+}
+
+// prepareUnionInput resolves the Parquet patterns of --union into a single input reading every file
+// they match. A file matched by several patterns is read once.
+func prepareUnionInput(cmd *cobra.Command, patterns []string, tempDir string, opts tableOptions) (inputTable, error) {
+	input := inputTable{name: TableName, path: strings.Join(patterns, " "), fileFormat: Parquet, opts: opts}
+	for _, pattern := range patterns {
+		format := determineFileFormat(pattern)
+		if f := cmd.Flag("format").Value.String(); f != "" {
+			var err error
+			if format, err = parseFileFormat(f); err != nil {
+				return input, err
+			}
+		}
+		if format != Parquet {
+			return input, fmt.Errorf("--union is only supported for Parquet files, not %s", pattern)
+		}
+		if isRemotePath(pattern) {
+			input.opts.remote = true
+		}
+	}
+	fmt.Fprintf(statusOut, "Detected file format: %s (%s)\n", input.fileFormat, input.path)
+
+	var local []string
+	for _, pattern := range patterns {
+		// DuckDB expands remote patterns itself
+		if isRemotePath(pattern) {
+			input.files = append(input.files, pattern)
+		} else {
+			local = append(local, pattern)
+		}
+	}
+	if len(local) > 0 {
+		var files []string
+		var err error
+		if cmd.Flag("no-glob").Value.String() == "true" {
+			for _, f := range local {
+				if files, err = processInputFiles(f, Parquet, tempDir, true); err != nil {
+					return input, err
+				}
+				if !slices.Contains(input.files, files[0]) {
+					input.files = append(input.files, files[0])
+				}
+			}
+		} else {
+			if files, err = matchParquetFiles(local); err != nil {
+				return input, err
+			}
+			input.files = append(input.files, files...)
+		}
+	}
+	return configureInput(cmd, input, tempDir)
+}
+
+// configureInput applies the read flags to an input whose format and files are known
+func configureInput(cmd *cobra.Command, input inputTable, tempDir string) (inputTable, error) {
+	if input.opts.randomFile && !unusedDefault(cmd, "random-file", input.fileFormat != Delta && input.fileFormat != Iceberg) {
+		if input.fileFormat == Delta || input.fileFormat == Iceberg {
+			return input, fmt.Errorf("--random-file is not supported for %s tables", input.fileFormat)
+		}
+		if input.opts.remote && isGlobPattern(input.files[0]) {
+			return input, fmt.Errorf("--random-file cannot pick from a remote pattern, which DuckDB expands itself")
+		}
+		if len(input.files) > 1 {
+			file := pickRandomFile(input.files, input.opts.sampleSeed)
+			fmt.Fprintf(os.Stderr, "Picked %s at random from %d file(s)\n", file, len(input.files))
+			input.files = []string{file}
+		}
+	}
+
+	files := input.files
+	var err error
+	if input.opts.strict && input.fileFormat == Parquet && len(files) > 1 {
+		if err := checkParquetSchemas(sessionSetup(input.fileFormat, input.opts), files); err != nil {
+			return input, err
+		}
+	}
+
+	if limit := cmd.Flag("limit-bytes").Value.String(); limit != "" &&
+		!unusedDefault(cmd, "limit-bytes", input.fileFormat == CSV && !isCompressed(files[0]) && !input.opts.remote) {
+		if input.fileFormat != CSV || isCompressed(files[0]) || input.opts.remote {
+			return input, fmt.Errorf("--limit-bytes is only supported for local uncompressed CSV files")
+		}
+		n, err := parseByteSize(limit)
+		if err != nil {
+			return input, err
+		}
+		if files[0], err = limitFileBytes(files[0], tempDir, n); err != nil {
+			return input, err
+		}
+		fmt.Fprintf(statusOut, "Reading the first %s of %s\n", formatBytes(n), input.path)
+	}
+	if unusedDefault(cmd, "union-by-name", input.fileFormat == Parquet || input.fileFormat == CSV) {
+		input.opts.unionByName = false
+	}
+	if input.opts.unionByName && input.fileFormat != Parquet && input.fileFormat != CSV {
+		return input, fmt.Errorf("--union-by-name is only supported for Parquet and CSV files")
+	}
+	if unusedDefault(cmd, "limit-per-file", input.fileFormat == Parquet || input.fileFormat == CSV) {
+		input.opts.limitPerFile = 0
+	}
+	if input.opts.limitPerFile > 0 && input.fileFormat != Parquet && input.fileFormat != CSV {
+		return input, fmt.Errorf("--limit-per-file is only supported for Parquet and CSV files")
+	}
+	if unusedDefault(cmd, "with-filename", input.fileFormat == Parquet || input.fileFormat == CSV) {
+		input.opts.withFilename = false
+	}
+	if input.opts.withFilename {
+		if input.fileFormat != Parquet && input.fileFormat != CSV {
+			return input, fmt.Errorf("--with-filename is only supported for Parquet and CSV files")
+		}
+		if cmd.Flag("relative-paths").Value.String() == "true" {
+			input.opts.filenamePrefix = commonDirPrefix(files)
+		}
+	}
+	if input.fileFormat == CSV || input.fileFormat == JSON {
+		input.opts.compression = readCompression(files[0])
+	}
+	if cmd.Flag("trim").Value.String() == "true" && !unusedDefault(cmd, "trim", input.fileFormat == CSV) {
+		if input.fileFormat != CSV {
+			return input, fmt.Errorf("--trim is only supported for CSV files")
+		}
+		input.opts.trim = true
+	}
+	if cmd.Flag("flatten").Value.String() == "true" && !unusedDefault(cmd, "flatten", input.fileFormat == JSON) {
+		if input.fileFormat != JSON {
+			return input, fmt.Errorf("--flatten is only supported for JSON files")
+		}
+		depth, err := cmd.Flags().GetInt("flatten-depth")
+		if err != nil || depth < 1 {
+			return input, fmt.Errorf("--flatten-depth must be at least 1")
+		}
+		input.opts.flattenDepth = depth
+	}
+	if flagGiven(cmd, "delim") && !unusedDefault(cmd, "delim", input.fileFormat == CSV) {
+		if input.fileFormat != CSV {
+			return input, fmt.Errorf("--delim is only supported for CSV files")
+		}
+		if input.opts.delim, err = parseDelimiter(cmd.Flag("delim").Value.String()); err != nil {
+			return input, err
+		}
+	} else if input.fileFormat == CSV && !input.opts.remote {
+		if input.opts.delim, err = sniffDelimiter(files[0]); err != nil {
+			return input, err
+		}
+		if input.opts.delim == "\t" {
+			fmt.Fprintf(statusOut, "Detected tab separated values in %s\n", input.path)
+		}
+	}
+	if flagGiven(cmd, "date-format") && !unusedDefault(cmd, "date-format", input.fileFormat == CSV) {
+		if input.opts.dateFormat, err = csvFormatFlag(cmd, "date-format", input.fileFormat); err != nil {
+			return input, err
+		}
+	}
+	if flagGiven(cmd, "timestamp-format") && !unusedDefault(cmd, "timestamp-format", input.fileFormat == CSV) {
+		if input.opts.timestampFormat, err = csvFormatFlag(cmd, "timestamp-format", input.fileFormat); err != nil {
+			return input, err
+		}
+	}
+	if size := cmd.Flag("max-line-size").Value.String(); size != "" && !unusedDefault(cmd, "max-line-size", input.fileFormat == CSV) {
+		if input.fileFormat != CSV {
+			return input, fmt.Errorf("--max-line-size is only supported for CSV files")
+		}
+		if input.opts.maxLineSize, err = parseByteSize(size); err != nil {
+			return input, err
+		}
+	}
+	if cmd.Flag("fast").Value.String() == "true" && !unusedDefault(cmd, "fast", input.fileFormat == CSV && !input.opts.remote) {
+		if input.fileFormat != CSV || input.opts.remote {
+			return input, fmt.Errorf("--fast is only supported for local CSV files")
+		}
+		if input.opts.fastColumns, err = readCSVHeader(files[0], input.opts.delim); err != nil {
+			return input, err
+		}
+	}
+	if cmd.Flag("no-header").Value.String() == "true" && !unusedDefault(cmd, "no-header", input.fileFormat == CSV) {
+		if input.fileFormat != CSV {
+			return input, fmt.Errorf("--no-header is only supported for CSV files")
+		}
+		if len(input.opts.fastColumns) > 0 {
+			return input, fmt.Errorf("--no-header cannot be combined with --fast, which reads the header itself")
+		}
+		input.opts.noHeader = true
+	} else if input.fileFormat == CSV && !input.opts.remote && len(input.opts.fastColumns) == 0 {
+		if err := checkSingleColumnHeader(input); err != nil {
+			return input, err
+		}
+	}
+	if flagGiven(cmd, "sample-size") && !unusedDefault(cmd, "sample-size", input.fileFormat == CSV) {
+		if input.fileFormat != CSV {
+			return input, fmt.Errorf("--sample-size is only supported for CSV files")
+		}
+		n, err := cmd.Flags().GetInt64("sample-size")
+		if err != nil || n == 0 || n < -1 {
+			return input, fmt.Errorf("--sample-size must be a positive number of rows, or -1 for the whole file")
+		}
+		input.opts.sampleSize = n
+	} else if input.fileFormat == CSV && !input.opts.remote && !isCompressed(files[0]) && len(input.opts.fastColumns) == 0 {
+		rows, lineSize, err := boundedSampleSize(files[0])
+		if err != nil {
+			return input, err
+		}
+		if rows > 0 {
+			input.opts.sampleSize = rows
+			fmt.Fprintf(statusOut, "Note: the lines of %s average %s, detecting its types from the first %d rows (--sample-size)\n",
+				input.path, formatBytes(lineSize), rows)
+		}
+	}
+
+	if version := cmd.Flag("version-as-of").Value.String(); version != "" && !unusedDefault(cmd, "version-as-of", input.fileFormat == Delta) {
+		if input.fileFormat != Delta {
+			return input, fmt.Errorf("--version-as-of is only supported for Delta tables")
+		}
+		if _, err := strconv.ParseUint(version, 10, 64); err != nil {
+			return input, fmt.Errorf("invalid --version-as-of '%s': expected a non-negative table version", version)
+		}
+		input.opts.versionAsOf = version
+	}
+	if snapshot := cmd.Flag("snapshot").Value.String(); snapshot != "" && !unusedDefault(cmd, "snapshot", input.fileFormat == Iceberg) {
+		if input.fileFormat != Iceberg {
+			return input, fmt.Errorf("--snapshot is only supported for Iceberg tables")
+		}
+		if _, err := strconv.ParseUint(snapshot, 10, 64); err != nil {
+			return input, fmt.Errorf("invalid --snapshot '%s': expected a numeric snapshot id", snapshot)
+		}
+		input.opts.snapshot = snapshot
+	}
+	// A version or snapshot given as an argument wins over DPI_AS_OF
+	timeTravel := input.opts.versionAsOf == "" && input.opts.snapshot == ""
+	if asOf := cmd.Flag("as-of").Value.String(); asOf != "" && !unusedDefault(cmd, "as-of", timeTravel && (input.fileFormat == Delta || input.fileFormat == Iceberg)) {
+		if err := setAsOf(&input.opts, input.fileFormat, asOf); err != nil {
+			return input, err
+		}
+	}
+
+	if ext := requiredExtension(input.fileFormat); ext != "" {
+		if err := ensureExtension(ext, !input.opts.noAutoload); err != nil {
+			return input, err
+		}
+	}
+	if input.opts.remote {
+		if err := ensureExtension("httpfs", !input.opts.noAutoload); err != nil {
+			return input, err
+		}
+	}
+
+	if filter := cmd.Flag("partition-filter").Value.String(); filter != "" {
+		if input.opts.partitionFilter, err = parsePartitionFilter(filter); err != nil {
+			return input, err
+		}
+		if err := validatePartitionFilter(input.opts.partitionFilter, files); err != nil {
+			return input, err
+		}
+	}
+	return input, nil
+}
+
+// expandRange replaces the {} in the template with every number of a range such as 0-9. When both
+// bounds are written with the same number of digits, as in 00000-00099, the numbers are zero-padded
+// to that width. Every resulting file must exist.
+func expandRange(template string, spec string) ([]string, error) {
+	if strings.Count(template, "{}") != 1 {
+		return nil, fmt.Errorf("--range needs a path with exactly one {} placeholder, got '%s'", template)
+	}
+	from, to, ok := strings.Cut(spec, "-")
+	start, err1 := strconv.Atoi(from)
+	end, err2 := strconv.Atoi(to)
+	if !ok || err1 != nil || err2 != nil || start < 0 || end < start {
+		return nil, fmt.Errorf("invalid --range '%s': expected <start>-<end> with start <= end", spec)
+	}
+	width := 0
+	if len(from) == len(to) {
+		width = len(from)
+	}
+
+	files := make([]string, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		f := strings.Replace(template, "{}", fmt.Sprintf("%0*d", width, i), 1)
+		if !fileExists(f) {
+			return nil, fmt.Errorf("file does not exist: %s", f)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// commonDirPrefix returns the directory all files are in, with a trailing separator, or "" when they
+// share none. A single file gives its own directory.
+func commonDirPrefix(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	common := strings.Split(filepath.Dir(files[0]), string(filepath.Separator))
+	for _, f := range files[1:] {
+		parts := strings.Split(filepath.Dir(f), string(filepath.Separator))
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	dir := strings.Join(common, string(filepath.Separator))
+	if dir == "" || dir == "." {
+		return ""
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return dir
+}
+
+// csvFormatFlag returns the value of a CSV date or timestamp format flag, which must not be empty
+func csvFormatFlag(cmd *cobra.Command, name string, fileFormat FileFormat) (string, error) {
+	if fileFormat != CSV {
+		return "", fmt.Errorf("--%s is only supported for CSV files", name)
+	}
+	value := cmd.Flag(name).Value.String()
+	if strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("--%s must not be empty", name)
+	}
+	return value, nil
+}
+
+var nonIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// tableNameFor derives a table name from the base name of the input up to its first dot, e.g.
+// "sales-2024.csv.gz" becomes sales_2024. Names that would be empty, such as for a "*.parquet" pattern,
+// fall back to <format>_data, and names starting with a digit are prefixed with t_.
+func tableNameFor(in inputTable) string {
+	base, _, _ := strings.Cut(filepath.Base(filepath.Clean(in.path)), ".")
+	name := strings.Trim(nonIdentifierChars.ReplaceAllString(base, "_"), "_")
+	if name == "" {
+		return string(in.fileFormat) + "_data"
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "t_" + name
+	}
+	return name
+}
+
+// assignTableNames gives every input its own table name, numbering names that would collide
+func assignTableNames(inputs []inputTable) {
+	used := make(map[string]bool, len(inputs))
+	for i := range inputs {
+		base := tableNameFor(inputs[i])
+		name := base
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[strings.ToLower(name)] = true
+		inputs[i].name = name
+	}
+}
+
+// asOfTimestampLayouts are the timestamp forms accepted by --as-of
+var asOfTimestampLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// setAsOf routes an --as-of value to the time travel option of the table format: a number is a Delta
+// version or an Iceberg snapshot id, anything else must be a timestamp, which only Iceberg supports
+func setAsOf(opts *tableOptions, fileFormat FileFormat, asOf string) error {
+	if fileFormat != Delta && fileFormat != Iceberg {
+		return fmt.Errorf("--as-of is only supported for Delta and Iceberg tables, %s has no time travel", fileFormat)
+	}
+	if _, err := strconv.ParseUint(asOf, 10, 64); err == nil {
+		if fileFormat == Delta {
+			opts.versionAsOf = asOf
+		} else {
+			opts.snapshot = asOf
+		}
+		return nil
+	}
+
+	valid := false
+	for _, layout := range asOfTimestampLayouts {
+		if _, err := time.Parse(layout, asOf); err == nil {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid --as-of '%s': expected a version, a snapshot id or a timestamp like 2024-06-01 12:00:00", asOf)
+	}
+	if fileFormat == Delta {
+		return fmt.Errorf("--as-of %s: Delta tables can only be read as of a version", asOf)
+	}
+	opts.snapshotTimestamp = asOf
+	return nil
+}
+
+// readColumnsFile reads a --columns-file: one column name per line, skipping blank lines and lines
+// starting with #
+func readColumnsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --columns-file: %w", err)
+	}
+	var columns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			columns = append(columns, line)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("--columns-file %s lists no columns", path)
+	}
+	return columns, nil
+}
+
+// readRenameMap reads a --rename-map file: one old,new pair of column names per line, with an optional
+// old,new header line
+func readRenameMap(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open --rename-map: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --rename-map %s: %w", path, err)
+	}
+	if len(records) > 0 && strings.EqualFold(records[0][0], "old") && strings.EqualFold(records[0][1], "new") {
+		records = records[1:]
+	}
+
+	renames := make(map[string]string, len(records))
+	for _, rec := range records {
+		old, name := strings.TrimSpace(rec[0]), strings.TrimSpace(rec[1])
+		if old == "" || name == "" {
+			return nil, fmt.Errorf("--rename-map %s: empty column name in %q", path, strings.Join(rec, ","))
+		}
+		if _, ok := renames[old]; ok {
+			return nil, fmt.Errorf("--rename-map %s: column '%s' is renamed twice", path, old)
+		}
+		renames[old] = name
+	}
+	return renames, nil
+}
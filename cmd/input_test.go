@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCSVFormatFlag(t *testing.T) {
+	tests := []struct {
+		value   string
+		format  FileFormat
+		want    string
+		wantErr bool
+	}{
+		{"%d.%m.%Y", CSV, "%d.%m.%Y", false},
+		{"%m/%d/%Y %I:%M %p", CSV, "%m/%d/%Y %I:%M %p", false},
+		{"", CSV, "", true},
+		{"  ", CSV, "", true},
+		{"%d.%m.%Y", Parquet, "", true},
+		{"%d.%m.%Y", JSON, "", true},
+	}
+	for _, tt := range tests {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("date-format", "", "")
+		if err := cmd.Flags().Set("date-format", tt.value); err != nil {
+			t.Fatal(err)
+		}
+		got, err := csvFormatFlag(cmd, "date-format", tt.format)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("csvFormatFlag(%q, %s) = %q, %v, want %q (error %v)", tt.value, tt.format, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSkipBOM(t *testing.T) {
+	tests := map[string]string{
+		"":                         "",
+		"id,name\n":                "id,name\n",
+		utf8BOM + "id,name\n":      "id,name\n",
+		utf8BOM:                    "",
+		utf8BOM + utf8BOM + "id\n": utf8BOM + "id\n",
+		"\xef\xbbid\n":             "\xef\xbbid\n",
+		"i" + utf8BOM:              "i" + utf8BOM,
+	}
+	for in, want := range tests {
+		got, err := io.ReadAll(skipBOM(strings.NewReader(in)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("skipBOM(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReadCSVHeaderBOM(t *testing.T) {
+	dir := t.TempDir()
+	plain := writeTestFile(t, dir, "bom.csv", utf8BOM+"id,name\n1,a\n")
+
+	gzPath := filepath.Join(dir, "bom.csv.gz")
+	f, err := os.Create(gzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := gzip.NewWriter(f)
+	w.Write([]byte(utf8BOM + "id;name\n1;a\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for path, delim := range map[string]string{plain: ",", gzPath: ";"} {
+		header, err := readCSVHeader(path, delim)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"id", "name"}; !reflect.DeepEqual(header, want) {
+			t.Errorf("readCSVHeader(%s) = %q, want %q", filepath.Base(path), header, want)
+		}
+	}
+}
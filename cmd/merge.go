@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <file or pattern>... -o <output>",
+	Short: "Concatenate many files into a single Parquet, CSV or JSON file",
+	Long: `Read all files matched by the arguments and write their rows to a single output file.
+The columns of the files are matched by position, or by name with --union-by-name, which also fills
+columns missing from some files with NULL. The output format follows the extension of the output file,
+like for dpi convert.`,
+	Example: `  dpi merge 'parts/*.parquet' -o merged.parquet
+  dpi merge --union-by-name --output-compression zstd 2023/*.parquet 2024/*.parquet -o all.parquet
+  dpi merge jan.csv feb.csv mar.csv -o q1.csv`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runExport,
+}
+
+func init() {
+	addExportFlags(mergeCmd)
+	rootCmd.AddCommand(mergeCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OutputFormat is the DuckDB CLI output mode used for query results with --output-format
+type OutputFormat string
+
+const (
+	OutputDuckbox  OutputFormat = "duckbox"
+	OutputBox      OutputFormat = "box"
+	OutputCSV      OutputFormat = "csv"
+	OutputJSON     OutputFormat = "json"
+	OutputNDJSON   OutputFormat = "ndjson"
+	OutputLine     OutputFormat = "line"
+	OutputList     OutputFormat = "list"
+	OutputMarkdown OutputFormat = "markdown"
+	OutputHTML     OutputFormat = "html"
+)
+
+var outputFormats = []OutputFormat{OutputDuckbox, OutputBox, OutputCSV, OutputJSON, OutputNDJSON, OutputLine, OutputList, OutputMarkdown, OutputHTML}
+
+func parseOutputFormat(s string) (OutputFormat, error) {
+	f := OutputFormat(strings.ToLower(s))
+	for _, known := range outputFormats {
+		if f == known {
+			return f, nil
+		}
+	}
+	names := make([]string, 0, len(outputFormats))
+	for _, known := range outputFormats {
+		names = append(names, string(known))
+	}
+	return "", fmt.Errorf("unsupported output format '%s' (expected one of %s)", s, strings.Join(names, ", "))
+}
+
+// outputFormatArgs returns the DuckDB CLI flags selecting the output format. duckbox is the
+// CLI's default mode and needs no flag.
+func outputFormatArgs(f OutputFormat) []string {
+	switch f {
+	case OutputDuckbox:
+		return nil
+	case OutputNDJSON:
+		// One object per line, printed as the rows arrive instead of as a single array
+		return []string{"-cmd", ".mode jsonlines"}
+	default:
+		return []string{"-" + string(f)}
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	for in, want := range map[string]OutputFormat{"ndjson": OutputNDJSON, "NDJSON": OutputNDJSON, "json": OutputJSON, "duckbox": OutputDuckbox, "markdown": OutputMarkdown} {
+		if got, err := parseOutputFormat(in); err != nil || got != want {
+			t.Errorf("parseOutputFormat(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "jsonlines", "xml"} {
+		if _, err := parseOutputFormat(in); err == nil {
+			t.Errorf("parseOutputFormat(%q) succeeded", in)
+		}
+	}
+}
+
+func TestOutputFormatArgs(t *testing.T) {
+	tests := map[OutputFormat][]string{
+		OutputDuckbox:  nil,
+		OutputNDJSON:   {"-cmd", ".mode jsonlines"},
+		OutputJSON:     {"-json"},
+		OutputCSV:      {"-csv"},
+		OutputBox:      {"-box"},
+		OutputLine:     {"-line"},
+		OutputList:     {"-list"},
+		OutputMarkdown: {"-markdown"},
+		OutputHTML:     {"-html"},
+	}
+	if len(tests) != len(outputFormats) {
+		t.Errorf("%d output formats are tested, want all %d", len(tests), len(outputFormats))
+	}
+	for format, want := range tests {
+		if got := outputFormatArgs(format); !reflect.DeepEqual(got, want) {
+			t.Errorf("outputFormatArgs(%s) = %q, want %q", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// rowGroupQuery summarizes the column chunks of every row group of a Parquet file
+const rowGroupQuery = `SELECT row_group_id, any_value(row_group_num_rows),
+	sum(total_compressed_size), sum(total_uncompressed_size),
+	array_to_string(list_sort(list_distinct(flatten(list(string_split(encodings, ', '))))), ', ')
+FROM parquet_metadata(%s) GROUP BY row_group_id ORDER BY row_group_id;`
+
+// printRowGroups prints the row count, compressed and uncompressed size and the column encodings of
+// every row group, one table per file. setup is run first, so remote files get their credentials.
+func printRowGroups(setup string, files []string) error {
+	for i, file := range files {
+		output, err := captureCommand([]string{"duckdb", "-csv", "-noheader", "-c", setup + fmt.Sprintf(rowGroupQuery, quoteLiteral(file))})
+		if err != nil {
+			return fmt.Errorf("failed to read the Parquet metadata of %s: %w", file, err)
+		}
+		records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+		if err != nil {
+			return fmt.Errorf("failed to parse the Parquet metadata of %s: %w", file, err)
+		}
+
+		if i > 0 {
+			fmt.Fprintln(os.Stdout)
+		}
+		fmt.Fprintf(os.Stdout, "%s: %d row group(s)\n", file, len(records))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ROW GROUP\tROWS\tCOMPRESSED\tUNCOMPRESSED\tENCODINGS")
+		for _, r := range records {
+			if len(r) != 5 {
+				return fmt.Errorf("unexpected Parquet metadata row for %s: %v", file, r)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r[0], r[1], sizeField(r[2]), sizeField(r[3]), r[4])
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+// sizeField formats a byte count returned by DuckDB, keeping the raw value when it is not a number
+func sizeField(s string) string {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return s
+	}
+	return formatBytes(n)
+}
+
+// parquetSchemaQuery lists the physical schema elements of a Parquet file
+const parquetSchemaQuery = `SELECT name, type, type_length, repetition_type, num_children, converted_type, scale, precision, logical_type
+FROM parquet_schema(%s);`
+
+// printParquetSchema prints the physical Parquet schema of every file: physical and converted types,
+// repetition and the logical type annotation, as opposed to the DuckDB types shown by --schema
+func printParquetSchema(setup string, files []string, outputArgs []string) error {
+	for i, file := range files {
+		if i > 0 {
+			fmt.Fprintln(os.Stdout)
+		}
+		fmt.Fprintf(os.Stdout, "%s:\n", file)
+
+		cmds := append([]string{"duckdb"}, outputArgs...)
+		cmds = append(cmds, "-c", setup+fmt.Sprintf(parquetSchemaQuery, quoteLiteral(file)))
+		if err := executeCommand(cmds); err != nil {
+			return fmt.Errorf("failed to read the Parquet schema of %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// parquetSchemaSummaryQuery describes the schema of every file as one string of its elements in order,
+// leaving out the root element whose name depends on the writer. parquet_schema returns the elements of
+// each file in schema order, which WITH ORDINALITY keeps as the element index.
+const parquetSchemaSummaryQuery = `SELECT file_name, string_agg(concat_ws(' ', name, type, logical_type, repetition_type), ', ' ORDER BY ordinal)
+FROM (SELECT *, row_number() OVER (PARTITION BY file_name ORDER BY ordinality) AS ordinal FROM parquet_schema([%s]) WITH ORDINALITY)
+WHERE ordinal > 1 GROUP BY file_name ORDER BY file_name;`
+
+// checkParquetSchemas verifies that all files have the same physical schema, so a pattern matching files
+// written with a different schema fails instead of being unioned silently
+func checkParquetSchemas(setup string, files []string) error {
+	quoted := make([]string, 0, len(files))
+	for _, f := range files {
+		quoted = append(quoted, quoteLiteral(f))
+	}
+	output, err := captureCommand([]string{"duckdb", "-csv", "-noheader", "-c", setup + fmt.Sprintf(parquetSchemaSummaryQuery, strings.Join(quoted, ", "))})
+	if err != nil {
+		return fmt.Errorf("failed to read the Parquet schemas: %w", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to parse the Parquet schemas: %w", err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("failed to read the Parquet schemas: no schema elements were returned")
+	}
+
+	for _, r := range records[1:] {
+		if r[1] != records[0][1] {
+			return fmt.Errorf("--strict: the schema of %s differs from %s\n  %s: %s\n  %s: %s",
+				r[0], records[0][0], records[0][0], records[0][1], r[0], r[1])
+		}
+	}
+	return nil
+}
+
+// parquetStatsRangeQuery combines the minimum and maximum statistics of a column over all row groups.
+// The first value counts the row groups whose statistics are missing or cannot be cast to the column type.
+const parquetStatsRangeQuery = `SELECT
+	count(*) FILTER (WHERE (stats_min_value IS NULL OR stats_max_value IS NULL) AND stats_null_count IS DISTINCT FROM num_values
+		OR stats_min_value IS NOT NULL AND TRY_CAST(stats_min_value AS %[3]s) IS NULL
+		OR stats_max_value IS NOT NULL AND TRY_CAST(stats_max_value AS %[3]s) IS NULL),
+	count(*), min(TRY_CAST(stats_min_value AS %[3]s))::VARCHAR, max(TRY_CAST(stats_max_value AS %[3]s))::VARCHAR
+FROM parquet_metadata([%[1]s]) WHERE path_in_schema = %[2]s;`
+
+// parquetStatsRange returns the minimum and maximum of the column from the row group statistics in the
+// footers of the files, without reading any data. ok is false when some row group has no usable
+// statistics for it or the metadata cannot be read, so the files have to be scanned instead. setup is
+// run first so remote files get their credentials and --utc applies to the printed values.
+func parquetStatsRange(setup string, files []string, col column) (lo string, hi string, ok bool, err error) {
+	quoted := make([]string, 0, len(files))
+	for _, f := range files {
+		quoted = append(quoted, quoteLiteral(f))
+	}
+	output, err := captureCommand([]string{"duckdb", "-csv", "-noheader", "-c",
+		setup + fmt.Sprintf(parquetStatsRangeQuery, strings.Join(quoted, ", "), quoteLiteral(col.Name), col.Type)})
+	if err != nil {
+		fmt.Fprintln(statusOut, "Could not read the Parquet statistics, scanning the data instead")
+		return "", "", false, nil
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil || len(records) != 1 || len(records[0]) != 4 {
+		return "", "", false, fmt.Errorf("unexpected Parquet statistics output: %q", output)
+	}
+	r := records[0]
+	if r[0] != "0" || r[1] == "0" {
+		return "", "", false, nil
+	}
+	return r[2], r[3], true, nil
+}
+
+// parquetUncompressedSize returns the total uncompressed size of the column chunks of the files, read
+// from their footers
+func parquetUncompressedSize(setup string, files []string) (int64, error) {
+	quoted := make([]string, 0, len(files))
+	for _, f := range files {
+		quoted = append(quoted, quoteLiteral(f))
+	}
+	output, err := captureCommand([]string{"duckdb", "-csv", "-noheader", "-c",
+		setup + fmt.Sprintf("SELECT coalesce(sum(total_uncompressed_size), 0) FROM parquet_metadata([%s]);", strings.Join(quoted, ", "))})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the Parquet metadata: %w", err)
+	}
+	result := strings.TrimSpace(string(output))
+	size, err := strconv.ParseInt(result, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected Parquet size %q: %w", result, err)
+	}
+	return size, nil
+}
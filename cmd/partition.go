@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// partitionPredicate restricts a Hive partition column to one value
+type partitionPredicate struct {
+	Key   string
+	Value string
+}
+
+// parsePartitionFilter parses a filter such as "year=2024,month=01"
+func parsePartitionFilter(filter string) ([]partitionPredicate, error) {
+	var predicates []partitionPredicate
+	for _, part := range strings.Split(filter, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid partition filter '%s': expected key=value", part)
+		}
+		predicates = append(predicates, partitionPredicate{Key: key, Value: value})
+	}
+	return predicates, nil
+}
+
+// hivePartitionKeys returns the partition column names found in the key=value directories of the files
+func hivePartitionKeys(files []string) map[string]bool {
+	keys := make(map[string]bool)
+	for _, f := range files {
+		for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(f)), "/") {
+			if key, _, ok := strings.Cut(dir, "="); ok && key != "" {
+				keys[key] = true
+			}
+		}
+	}
+	return keys
+}
+
+// validatePartitionFilter checks that every filtered key is a partition column of the files
+func validatePartitionFilter(predicates []partitionPredicate, files []string) error {
+	keys := hivePartitionKeys(files)
+	if len(keys) == 0 {
+		return fmt.Errorf("--partition-filter requires Hive partitioned files (directories named key=value)")
+	}
+	for _, p := range predicates {
+		if !keys[p.Key] {
+			known := make([]string, 0, len(keys))
+			for k := range keys {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("'%s' is not a partition column (available: %s)", p.Key, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// partitionWhereClause builds the WHERE condition for the predicates. Several values for the
+// same key are combined with IN, different keys with AND.
+func partitionWhereClause(predicates []partitionPredicate) string {
+	var order []string
+	values := make(map[string][]string)
+	for _, p := range predicates {
+		if _, ok := values[p.Key]; !ok {
+			order = append(order, p.Key)
+		}
+		values[p.Key] = append(values[p.Key], quoteLiteral(p.Value))
+	}
+
+	conditions := make([]string, 0, len(order))
+	for _, key := range order {
+		if len(values[key]) == 1 {
+			conditions = append(conditions, fmt.Sprintf("%s = %s", quoteIdentifier(key), values[key][0]))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("%s IN (%s)", quoteIdentifier(key), strings.Join(values[key], ", ")))
+		}
+	}
+	return strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// perFileResult is the outcome of running the query against one file
+type perFileResult struct {
+	file string
+	err  error
+}
+
+// splitPerFile turns every input into one input per file, each loaded as table p on its own
+func splitPerFile(inputs []inputTable) []inputTable {
+	var split []inputTable
+	for _, in := range inputs {
+		for _, f := range in.files {
+			single := in
+			single.name = TableName
+			single.files = []string{f}
+			split = append(split, single)
+		}
+	}
+	return split
+}
+
+// defaultParallel is the default number of files --per-file loads at once. Every duckdb process uses
+// all cores by itself, so a few at a time are enough to hide the start-up and I/O waits.
+func defaultParallel() int {
+	return min(runtime.GOMAXPROCS(0), 4)
+}
+
+// runFile loads the file into its own database and, once wait hands it the writer, runs the query
+// against it with the output going straight to the writer. wait returns nil when the run stopped
+// before it was the file's turn, the query is not run then.
+func runFile(in inputTable, duckdbPath string, query string, outputArgs []string, wait func() io.Writer) error {
+	defer os.Remove(duckdbPath) // free the space before loading the next file
+	loadErr := createTemporaryTable(in.name, in.filename(), duckdbPath, in.fileFormat, in.opts)
+	w := wait()
+	if w == nil {
+		return nil
+	}
+	if loadErr != nil {
+		return loadErr
+	}
+
+	cmds := append([]string{"duckdb", duckdbPath}, outputArgs...)
+	cmd, cleanup, err := newCommand(append(cmds, "-c", query))
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	return nil
+}
+
+// fileRunner runs the query against one file for runPerFile. It is runFile, tests replace it to
+// control the output and timing of every file.
+var fileRunner = runFile
+
+// sourceVariable is the DuckDB variable holding the file name for the source column of --concat-output
+const sourceVariable = "dpi_source"
+
+// concatOutput appends the results of --concat-output to a single stream, one file after the other.
+// CSV results start with the same header, which is only written for the first file.
+type concatOutput struct {
+	w      io.Writer
+	csv    bool
+	header []byte
+}
+
+// file returns the writer appending the result of the next file
+func (c *concatOutput) file() *concatFile {
+	return &concatFile{c: c, headerDone: !c.csv}
+}
+
+// concatFile writes the result of one file to the concatOutput as it arrives. The first line of a CSV
+// result is held back until it is complete, to compare it with the header of the first file.
+type concatFile struct {
+	c          *concatOutput
+	line       []byte
+	headerDone bool
+	err        error
+}
+
+// Write never fails, so the query keeps running to its end; the first error is returned by close
+func (f *concatFile) Write(p []byte) (int, error) {
+	n := len(p)
+	if f.err != nil {
+		return n, nil
+	}
+	if !f.headerDone {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			f.line = append(f.line, p...)
+			return n, nil
+		}
+		f.line = append(f.line, p[:i]...)
+		if f.err = f.writeHeader(); f.err != nil {
+			return n, nil
+		}
+		p = p[i+1:]
+	}
+	if _, err := f.c.w.Write(p); err != nil {
+		f.err = fmt.Errorf("failed to write the result: %w", err)
+	}
+	return n, nil
+}
+
+// writeHeader writes the first line of the result unless it is the header already written. A CSV
+// result with other columns than the first one cannot be appended and is an error.
+func (f *concatFile) writeHeader() error {
+	f.headerDone = true
+	if f.c.header != nil {
+		if !bytes.Equal(f.line, f.c.header) {
+			return fmt.Errorf("the result has other columns than the first file, so it cannot be appended to the same CSV")
+		}
+		return nil
+	}
+	f.c.header = f.line
+	if _, err := f.c.w.Write(append(f.line, '\n')); err != nil {
+		return fmt.Errorf("failed to write the result: %w", err)
+	}
+	return nil
+}
+
+// close finishes the result of the file, whose only line may have had no line break
+func (f *concatFile) close() error {
+	if f.err == nil && !f.headerDone && len(f.line) > 0 {
+		f.err = f.writeHeader()
+	}
+	return f.err
+}
+
+// runPerFile loads every file into its own database and runs the query against it, printing a
+// header before each result, or appending the results to concat when it is set. Up to parallel files
+// are loaded at once, but each query only runs when the results of the files before it are written,
+// so the results stream out in file order. Unless keepGoing is set, the first failure stops the run. A summary is printed at the end and an error is returned when any
+// file failed.
+func runPerFile(inputs []inputTable, tempDir string, query string, outputArgs []string, keepGoing bool, parallel int, concat *concatOutput) error {
+	split := splitPerFile(inputs)
+	errs := make([]error, len(split))
+	// The writer is sent on turns[i] when it is the turn of file i, the channel is closed instead
+	// when the run stopped before
+	turns := make([]chan io.Writer, len(split))
+	done := make([]chan struct{}, len(split))
+	for i := range split {
+		turns[i] = make(chan io.Writer, 1)
+		done[i] = make(chan struct{})
+	}
+
+	// Files are handed out in order and no new one after a failure, so every file up to the
+	// first failure is run, like they are one after the other
+	var stop atomic.Bool
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range split {
+			if stop.Load() {
+				return
+			}
+			jobs <- i
+		}
+	}()
+	var wg sync.WaitGroup
+	for range parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				duckdbPath := filepath.Join(tempDir, fmt.Sprintf("file%d.duckdb", i))
+				fileQuery := query
+				if concat != nil {
+					fileQuery = fmt.Sprintf("SET VARIABLE %s = %s; ", sourceVariable, quoteLiteral(split[i].files[0])) + query
+				}
+				errs[i] = fileRunner(split[i], duckdbPath, fileQuery, outputArgs, func() io.Writer { return <-turns[i] })
+				if errs[i] != nil && !keepGoing {
+					stop.Store(true)
+				}
+				close(done[i])
+			}
+		}()
+	}
+
+	var results []perFileResult
+	next := 0
+	for i, in := range split {
+		file := in.files[0]
+		next = i + 1
+		if concat == nil {
+			fmt.Fprintf(os.Stdout, "==> %s <==\n", file)
+			turns[i] <- os.Stdout
+			<-done[i]
+		} else {
+			w := concat.file()
+			turns[i] <- w
+			<-done[i]
+			if errs[i] == nil {
+				errs[i] = w.close()
+			}
+		}
+
+		results = append(results, perFileResult{file: file, err: errs[i]})
+		if errs[i] != nil {
+			reportError(fmt.Sprintf("%s: %v", file, errs[i]), 1)
+			if !keepGoing {
+				stop.Store(true) // a failed write is only noticed here, after the query
+				break
+			}
+		}
+	}
+	// Files loaded after a failure wait for a turn that never comes
+	for _, turn := range turns[next:] {
+		close(turn)
+	}
+	wg.Wait()
+
+	var failed []perFileResult
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, r)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\n%d file(s) succeeded, %d failed\n", len(results)-len(failed), len(failed))
+	for _, r := range failed {
+		fmt.Fprintf(os.Stderr, "  FAILED %s: %v\n", r.file, r.err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("query failed for %d file(s)", len(failed))
+	}
+	return nil
+}
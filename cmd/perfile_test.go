@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRunner replaces fileRunner for the test. run returns the output of the file with the given
+// index, which is written in its turn. The files it was called for are recorded.
+func fakeRunner(t *testing.T, run func(i int) ([]byte, error)) *[]string {
+	t.Helper()
+	var mu sync.Mutex
+	var called []string
+	saved := fileRunner
+	fileRunner = func(in inputTable, duckdbPath string, query string, outputArgs []string, wait func() io.Writer) error {
+		mu.Lock()
+		called = append(called, in.files[0])
+		mu.Unlock()
+		var i int
+		fmt.Sscanf(in.files[0], "f%d.csv", &i)
+		output, err := run(i)
+		w := wait()
+		if w == nil || err != nil {
+			return err
+		}
+		// Written in pieces like a streaming query, splitting the lines
+		for len(output) > 0 {
+			n := min(len(output), 3)
+			w.Write(output[:n])
+			output = output[n:]
+		}
+		return nil
+	}
+	t.Cleanup(func() { fileRunner = saved })
+	return &called
+}
+
+// perFileInputs returns one input matching n files named f0.csv, f1.csv, ...
+func perFileInputs(n int) []inputTable {
+	in := inputTable{name: TableName, fileFormat: CSV}
+	for i := range n {
+		in.files = append(in.files, fmt.Sprintf("f%d.csv", i))
+	}
+	return []inputTable{in}
+}
+
+// captureOutput runs f with stdout and stderr redirected and returns what was written to them
+func captureOutput(t *testing.T, f func()) (string, string) {
+	t.Helper()
+	read := func(target **os.File) (func() string, func()) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := *target
+		*target = w
+		var buf bytes.Buffer
+		done := make(chan struct{})
+		go func() {
+			io.Copy(&buf, r)
+			close(done)
+		}()
+		restore := func() {
+			w.Close()
+			*target = saved
+		}
+		return func() string { <-done; return buf.String() }, restore
+	}
+	stdout, restoreStdout := read(&os.Stdout)
+	stderr, restoreStderr := read(&os.Stderr)
+	func() {
+		defer restoreStdout()
+		defer restoreStderr()
+		f()
+	}()
+	return stdout(), stderr()
+}
+
+func TestRunPerFileKeepsFileOrder(t *testing.T) {
+	// Later files finish first
+	fakeRunner(t, func(i int) ([]byte, error) {
+		time.Sleep(time.Duration(8-i) * 5 * time.Millisecond)
+		return []byte(fmt.Sprintf("result %d\n", i)), nil
+	})
+
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runPerFile(perFileInputs(8), t.TempDir(), "SELECT 1;", nil, false, 4, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want strings.Builder
+	for i := range 8 {
+		fmt.Fprintf(&want, "==> f%d.csv <==\nresult %d\n", i, i)
+	}
+	if stdout != want.String() {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want.String())
+	}
+	if !strings.Contains(stderr, "8 file(s) succeeded, 0 failed") {
+		t.Errorf("summary missing from %q", stderr)
+	}
+}
+
+func TestRunPerFileKeepGoing(t *testing.T) {
+	called := fakeRunner(t, func(i int) ([]byte, error) {
+		if i%2 == 1 {
+			return nil, fmt.Errorf("broken %d", i)
+		}
+		return []byte(fmt.Sprintf("result %d\n", i)), nil
+	})
+
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runPerFile(perFileInputs(4), t.TempDir(), "SELECT 1;", nil, true, 2, nil)
+	})
+	if err == nil || err.Error() != "query failed for 2 file(s)" {
+		t.Errorf("error = %v, want the number of failed files", err)
+	}
+	if len(*called) != 4 {
+		t.Errorf("ran %v, want every file", *called)
+	}
+	for _, s := range []string{"result 0", "result 2"} {
+		if !strings.Contains(stdout, s) {
+			t.Errorf("output %q is missing %q", stdout, s)
+		}
+	}
+	for _, s := range []string{"Error: f1.csv: broken 1", "2 file(s) succeeded, 2 failed", "FAILED f3.csv: broken 3"} {
+		if !strings.Contains(stderr, s) {
+			t.Errorf("stderr %q is missing %q", stderr, s)
+		}
+	}
+}
+
+func TestRunPerFileStopsAtFirstFailure(t *testing.T) {
+	const files, parallel, failing = 20, 3, 2
+	called := fakeRunner(t, func(i int) ([]byte, error) {
+		if i == failing {
+			return nil, fmt.Errorf("broken")
+		}
+		time.Sleep(5 * time.Millisecond)
+		return []byte(fmt.Sprintf("result %d\n", i)), nil
+	})
+
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runPerFile(perFileInputs(files), t.TempDir(), "SELECT 1;", nil, false, parallel, nil)
+	})
+	if err == nil {
+		t.Fatal("the failure was not returned")
+	}
+	// The files already handed out when the failure is noticed still run, no others
+	if len(*called) > failing+parallel+1 {
+		t.Errorf("ran %d files after the failure: %v", len(*called), *called)
+	}
+	if strings.Contains(stdout, fmt.Sprintf("f%d.csv", failing+1)) {
+		t.Errorf("printed results after the failure:\n%s", stdout)
+	}
+	if !strings.Contains(stdout, "result 1\n") {
+		t.Errorf("results before the failure are missing:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "2 file(s) succeeded, 1 failed") {
+		t.Errorf("summary missing from %q", stderr)
+	}
+}
+
+func TestRunPerFileConcat(t *testing.T) {
+	fakeRunner(t, func(i int) ([]byte, error) {
+		time.Sleep(time.Duration(3-i) * 5 * time.Millisecond)
+		return []byte(fmt.Sprintf("source,n\nf%d.csv,%d\n", i, i)), nil
+	})
+
+	var out bytes.Buffer
+	var err error
+	captureOutput(t, func() {
+		err = runPerFile(perFileInputs(3), t.TempDir(), "SELECT 1;", nil, false, 3, &concatOutput{w: &out, csv: true})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "source,n\nf0.csv,0\nf1.csv,1\nf2.csv,2\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestConcatOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     bool
+		results []string
+		want    string
+		wantErr bool
+	}{
+		{"csv", true, []string{"a,b\n1,2\n", "a,b\n3,4\n5,6\n"}, "a,b\n1,2\n3,4\n5,6\n", false},
+		{"csv without rows", true, []string{"a,b\n", "a,b\n3,4\n"}, "a,b\n3,4\n", false},
+		{"csv header without line break", true, []string{"a,b", "a,b\n3,4\n"}, "a,b\n3,4\n", false},
+		{"csv with other columns", true, []string{"a,b\n1,2\n", "a,c\n3,4\n"}, "a,b\n1,2\n", true},
+		{"ndjson", false, []string{"{\"a\":1}\n", "{\"a\":2}\n"}, "{\"a\":1}\n{\"a\":2}\n", false},
+		{"empty results", true, []string{"", "a\n1\n"}, "a\n1\n", false},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		c := &concatOutput{w: &out, csv: tt.csv}
+		var err error
+		for _, r := range tt.results {
+			f := c.file()
+			// One byte at a time, the header must still be recognized
+			for i := range len(r) {
+				f.Write([]byte{r[i]})
+			}
+			if err = f.close(); err != nil {
+				break
+			}
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s: output = %q, want %q", tt.name, out.String(), tt.want)
+		}
+	}
+}
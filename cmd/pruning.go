@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// planNode is an operator of the JSON plan printed by EXPLAIN (ANALYZE, FORMAT json)
+type planNode struct {
+	OperatorType string                     `json:"operator_type"`
+	ExtraInfo    map[string]json.RawMessage `json:"extra_info"`
+	Children     []planNode                 `json:"children"`
+}
+
+// extraInfoList returns an extra_info entry of the plan, which DuckDB writes as a string when it has
+// a single value and as a list otherwise
+func (n planNode) extraInfoList(key string) []string {
+	raw, ok := n.ExtraInfo[key]
+	if !ok {
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil && s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+// scanPruning is what the analyzed plan tells about the filters of a query
+type scanPruning struct {
+	pushed      []string // filters evaluated by the Parquet reader, which can skip row groups
+	fileFilters []string // filters on the hive partitions, which skip whole files
+	remaining   []string // filters applied to the rows after the scan
+	filesRead   int
+}
+
+// collectPruning walks the plan and gathers the filters and file counts of its scans
+func collectPruning(n planNode, p *scanPruning) {
+	switch n.OperatorType {
+	case "TABLE_SCAN":
+		p.pushed = append(p.pushed, n.extraInfoList("Filters")...)
+		p.fileFilters = append(p.fileFilters, n.extraInfoList("File Filters")...)
+		if files := n.extraInfoList("Total Files Read"); len(files) == 1 {
+			if count, err := strconv.Atoi(files[0]); err == nil {
+				p.filesRead += count
+			}
+		}
+	case "FILTER":
+		p.remaining = append(p.remaining, n.extraInfoList("Expression")...)
+	}
+	for _, c := range n.Children {
+		collectPruning(c, p)
+	}
+}
+
+// printPruning runs the query with the --where condition under EXPLAIN ANALYZE and reports which
+// filters DuckDB pushed into the Parquet scan and how many of the files it read. DuckDB does not
+// report how many row groups the scan skipped, so that is not printed.
+func printPruning(input inputTable) error {
+	if input.fileFormat != Parquet {
+		return fmt.Errorf("--explain-pruning is only supported for Parquet files")
+	}
+	if input.opts.where == "" {
+		return fmt.Errorf("--explain-pruning requires --where")
+	}
+
+	query, err := buildSelectQuery(input.filename(), input.fileFormat, input.opts)
+	if err != nil {
+		return err
+	}
+	setup := sessionSetup(input.fileFormat, input.opts)
+	output, err := captureCommand([]string{"duckdb", "-csv", "-noheader", "-c",
+		fmt.Sprintf("%sEXPLAIN (ANALYZE, FORMAT json) SELECT count(*) FROM (%s);", setup, query)})
+	if err != nil {
+		return fmt.Errorf("failed to analyze the query: %w", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil || len(records) != 1 || len(records[0]) != 2 {
+		return fmt.Errorf("unexpected EXPLAIN ANALYZE output: %s", strings.TrimSpace(string(output)))
+	}
+	var plan planNode
+	if err := json.Unmarshal([]byte(records[0][1]), &plan); err != nil {
+		return fmt.Errorf("failed to parse the query plan: %w", err)
+	}
+	var pruning scanPruning
+	collectPruning(plan, &pruning)
+
+	// A remote pattern is a single entry of input.files, so the files are counted by DuckDB
+	output, err = captureCommand([]string{"duckdb", "-csv", "-noheader", "-c", setup +
+		fmt.Sprintf("SELECT count(DISTINCT file_name) FROM parquet_metadata([%s]);", input.filename())})
+	if err != nil {
+		return fmt.Errorf("failed to count the Parquet files: %w", err)
+	}
+	result := strings.TrimSpace(string(output))
+	total, err := strconv.Atoi(result)
+	if err != nil {
+		return fmt.Errorf("unexpected Parquet file count %q: %w", result, err)
+	}
+
+	if len(pruning.pushed) > 0 {
+		fmt.Fprintf(os.Stdout, "Pushed into the Parquet scan: %s\n", strings.Join(pruning.pushed, " AND "))
+	} else {
+		fmt.Fprintln(os.Stdout, "Pushed into the Parquet scan: nothing, every row group is read")
+	}
+	if len(pruning.fileFilters) > 0 {
+		fmt.Fprintf(os.Stdout, "Used to skip files: %s\n", strings.Join(pruning.fileFilters, " AND "))
+	}
+	if len(pruning.remaining) > 0 {
+		fmt.Fprintf(os.Stdout, "Filtered after the scan: %s\n", strings.Join(pruning.remaining, " AND "))
+	}
+	fmt.Fprintf(os.Stdout, "Files read: %d of %d\n", pruning.filesRead, total)
+	if len(pruning.pushed) > 0 {
+		fmt.Fprintln(os.Stdout, "Row groups skipped: not reported by DuckDB, the pushed filters skip the row groups whose min/max statistics exclude them")
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// The plans are trimmed from the output of EXPLAIN (ANALYZE, FORMAT json) in DuckDB 1.4
+const (
+	// A single pushed filter is written as a string
+	singleFilterPlan = `{
+  "extra_info": {},
+  "children": [{
+    "operator_type": "EXPLAIN_ANALYZE",
+    "extra_info": {},
+    "children": [{
+      "operator_type": "UNGROUPED_AGGREGATE",
+      "extra_info": {"Aggregates": "count_star()"},
+      "children": [{
+        "operator_name": "READ_PARQUET ",
+        "operator_type": "TABLE_SCAN",
+        "extra_info": {
+          "Function": "READ_PARQUET",
+          "Projections": "",
+          "Filters": "id>850000",
+          "Estimated Cardinality": "200000",
+          "Total Files Read": "1"
+        },
+        "operator_rows_scanned": 1000000,
+        "children": []
+      }]
+    }]
+  }]
+}`
+
+	// Several pushed filters are written as a list
+	filterListPlan = `{
+  "extra_info": {},
+  "children": [{
+    "operator_type": "EXPLAIN_ANALYZE",
+    "extra_info": {},
+    "children": [{
+      "operator_type": "UNGROUPED_AGGREGATE",
+      "extra_info": {"Aggregates": "count_star()"},
+      "children": [{
+        "operator_type": "TABLE_SCAN",
+        "extra_info": {
+          "Function": "READ_PARQUET",
+          "Projections": "",
+          "Filters": ["id>850000", "k=3"],
+          "Estimated Cardinality": "200000",
+          "Total Files Read": "4"
+        },
+        "children": []
+      }]
+    }]
+  }]
+}`
+
+	// A hive partition filter skips files and the rest of the condition is filtered after the scan
+	partitionPlan = `{
+  "extra_info": {},
+  "children": [{
+    "operator_type": "EXPLAIN_ANALYZE",
+    "extra_info": {},
+    "children": [{
+      "operator_type": "UNGROUPED_AGGREGATE",
+      "extra_info": {"Aggregates": "count_star()"},
+      "children": [{
+        "operator_type": "FILTER",
+        "extra_info": {"Expression": "((id % 2) = 0)", "Estimated Cardinality": "66"},
+        "children": [{
+          "operator_type": "TABLE_SCAN",
+          "extra_info": {
+            "Function": "READ_PARQUET",
+            "Projections": "id",
+            "File Filters": "(k = 1)",
+            "Scanning Files": "1/1",
+            "Estimated Cardinality": "334",
+            "Total Files Read": "1"
+          },
+          "children": []
+        }]
+      }]
+    }]
+  }]
+}`
+
+	// Nothing is pushed into the scan
+	noFilterPlan = `{
+  "extra_info": {},
+  "children": [{
+    "operator_type": "FILTER",
+    "extra_info": {"Expression": ["(lower(name) = 'x')", "(id > 1)"]},
+    "children": [{
+      "operator_type": "TABLE_SCAN",
+      "extra_info": {"Function": "READ_PARQUET", "Filters": "", "Total Files Read": "2"},
+      "children": []
+    }]
+  }]
+}`
+)
+
+func TestCollectPruning(t *testing.T) {
+	tests := []struct {
+		name string
+		plan string
+		want scanPruning
+	}{
+		{"single filter", singleFilterPlan, scanPruning{pushed: []string{"id>850000"}, filesRead: 1}},
+		{"filter list", filterListPlan, scanPruning{pushed: []string{"id>850000", "k=3"}, filesRead: 4}},
+		{"partition filter", partitionPlan, scanPruning{
+			fileFilters: []string{"(k = 1)"},
+			remaining:   []string{"((id % 2) = 0)"},
+			filesRead:   1,
+		}},
+		{"no pushed filter", noFilterPlan, scanPruning{
+			remaining: []string{"(lower(name) = 'x')", "(id > 1)"},
+			filesRead: 2,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var plan planNode
+			if err := json.Unmarshal([]byte(tt.plan), &plan); err != nil {
+				t.Fatalf("failed to parse the plan: %v", err)
+			}
+			var got scanPruning
+			collectPruning(plan, &got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectPruning() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtraInfoList(t *testing.T) {
+	node := planNode{ExtraInfo: map[string]json.RawMessage{
+		"String": json.RawMessage(`"a=1"`),
+		"List":   json.RawMessage(`["a=1", "b=2"]`),
+		"Empty":  json.RawMessage(`""`),
+		"Number": json.RawMessage(`3`),
+	}}
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"String", []string{"a=1"}},
+		{"List", []string{"a=1", "b=2"}},
+		{"Empty", nil},
+		{"Number", nil},
+		{"Missing", nil},
+	}
+
+	for _, tt := range tests {
+		if got := node.extraInfoList(tt.key); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extraInfoList(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
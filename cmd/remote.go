@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// remotePrefixes are the URL schemes DuckDB reads through the httpfs extension
+var remotePrefixes = []string{"s3://", "s3a://", "s3n://", "gs://", "gcs://", "r2://", "http://", "https://"}
+
+// isRemotePath reports whether the path is a URL read by DuckDB over the network rather than a local file.
+// Remote paths are passed to DuckDB as they are, which also expands globs in object store URLs.
+func isRemotePath(path string) bool {
+	lower := strings.ToLower(path)
+	for _, prefix := range remotePrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// s3Config holds the --s3-* flags used to create a DuckDB secret for S3 compatible object stores
+type s3Config struct {
+	endpoint  string // host[:port], without the scheme
+	useSSL    bool
+	region    string
+	keyID     string
+	secretKey string
+}
+
+func (c s3Config) configured() bool {
+	return c.endpoint != "" || c.region != "" || c.keyID != ""
+}
+
+// addS3Flags registers the --s3-* flags
+func addS3Flags(cmd *cobra.Command) {
+	cmd.Flags().String("s3-endpoint", "", "Endpoint of an S3 compatible store, e.g. http://localhost:9000 (http:// disables TLS)")
+	cmd.Flags().String("s3-region", "", "Region of the S3 bucket")
+	cmd.Flags().String("s3-access-key", "", "Access key id for S3 (default: DuckDB's own configuration)")
+	cmd.Flags().String("s3-secret-key", "", "Secret access key for S3, set DPI_S3_SECRET_KEY instead to keep it out of the shell history")
+	cmd.MarkFlagsRequiredTogether("s3-access-key", "s3-secret-key")
+}
+
+// readS3Config parses the --s3-* flags
+func readS3Config(cmd *cobra.Command) (s3Config, error) {
+	config := s3Config{
+		useSSL:    true,
+		region:    cmd.Flag("s3-region").Value.String(),
+		keyID:     cmd.Flag("s3-access-key").Value.String(),
+		secretKey: cmd.Flag("s3-secret-key").Value.String(),
+	}
+	if endpoint := cmd.Flag("s3-endpoint").Value.String(); endpoint != "" {
+		switch {
+		case strings.HasPrefix(endpoint, "http://"):
+			config.useSSL = false
+			endpoint = strings.TrimPrefix(endpoint, "http://")
+		case strings.HasPrefix(endpoint, "https://"):
+			endpoint = strings.TrimPrefix(endpoint, "https://")
+		case strings.Contains(endpoint, "://"):
+			return config, fmt.Errorf("invalid --s3-endpoint '%s': expected host[:port], optionally with http:// or https://", endpoint)
+		}
+		config.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+	if config.configured() && !slices.Contains(secretStatements, config.secretStatement()) {
+		secretStatements = append(secretStatements, config.secretStatement())
+	}
+	return config, nil
+}
+
+// secretStatement returns the CREATE SECRET statement for the configuration. The secret is temporary,
+// so it is never written to a --database.
+func (c s3Config) secretStatement() string {
+	options := []string{"TYPE s3"}
+	if c.keyID != "" {
+		options = append(options, "KEY_ID "+quoteLiteral(c.keyID), "SECRET "+quoteLiteral(c.secretKey))
+	}
+	if c.region != "" {
+		options = append(options, "REGION "+quoteLiteral(c.region))
+	}
+	if c.endpoint != "" {
+		// Self-hosted stores rarely support virtual host style bucket names
+		options = append(options, "ENDPOINT "+quoteLiteral(c.endpoint), "URL_STYLE 'path'")
+		if !c.useSSL {
+			options = append(options, "USE_SSL false")
+		}
+	}
+	return fmt.Sprintf("CREATE OR REPLACE TEMPORARY SECRET dpi_s3 (%s); ", strings.Join(options, ", "))
+}
+
+// secretStatements are the CREATE SECRET statements of the run, which hideSecrets keeps off the duckdb
+// command lines
+var secretStatements []string
+
+// hideSecrets moves the statements of a -c argument up to and including a secret into an init file
+// only the user can read, since the command line of a process is visible to every user of the machine.
+// An init file the arguments already name is appended to it. The returned function removes the file.
+func hideSecrets(args []string) ([]string, func(), error) {
+	for i := 1; i < len(args); i++ {
+		if args[i-1] != "-c" {
+			continue
+		}
+		for _, secret := range secretStatements {
+			end := strings.Index(args[i], secret)
+			if end < 0 {
+				continue
+			}
+			end += len(secret)
+			init := args[i][:end]
+			hidden := slices.Clone(args)
+			hidden[i] = args[i][end:]
+			if j := slices.Index(hidden, "-init"); j >= 0 && j+1 < len(hidden) {
+				data, err := os.ReadFile(hidden[j+1])
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to read init file: %w", err)
+				}
+				init += "\n" + string(data)
+				hidden = slices.Delete(hidden, j, j+2)
+			}
+
+			// CreateTemp creates the file with mode 0600
+			f, err := os.CreateTemp("", "dpi-init-*.sql")
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to write init file: %w", err)
+			}
+			_, err = f.WriteString(init + "\n")
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(f.Name())
+				return nil, nil, fmt.Errorf("failed to write init file: %w", err)
+			}
+			return slices.Insert(hidden, 1, "-init", f.Name()), func() { os.Remove(f.Name()) }, nil
+		}
+	}
+	return args, func() {}, nil
+}
+
+// maskSecrets replaces the S3 secret key in SQL that is shown to the user or written to a file
+func maskSecrets(sql string, opts tableOptions) string {
+	if opts.s3.secretKey == "" {
+		return sql
+	}
+	return strings.ReplaceAll(sql, quoteLiteral(opts.s3.secretKey), "'***'")
+}
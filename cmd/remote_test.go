@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHideSecrets(t *testing.T) {
+	config := s3Config{keyID: "AKIA", secretKey: "hunter2", region: "eu-west-1"}
+	secret := config.secretStatement()
+	saved := secretStatements
+	secretStatements = []string{secret}
+	t.Cleanup(func() { secretStatements = saved })
+
+	setup := "SET TimeZone = 'UTC'; LOAD httpfs; " + secret
+	query := "SELECT * FROM read_parquet(['s3://bucket/a.parquet']);"
+	args, cleanup, err := hideSecrets([]string{"duckdb", "db.duckdb", "-csv", "-c", setup + query})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.Join(args, " "), "hunter2") {
+		t.Errorf("the secret is still on the command line: %q", args)
+	}
+	if len(args) != 7 || args[1] != "-init" || !slices.Equal(args[3:], []string{"db.duckdb", "-csv", "-c", query}) {
+		t.Fatalf("args = %q, want the init file first and the query left in -c", args)
+	}
+
+	initFile := args[2]
+	info, err := os.Stat(initFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("init file mode = %o, want 600", perm)
+	}
+	if data, _ := os.ReadFile(initFile); string(data) != setup+"\n" {
+		t.Errorf("init file = %q, want the setup up to the secret", data)
+	}
+	cleanup()
+	if _, err := os.Stat(initFile); !os.IsNotExist(err) {
+		t.Error("the init file was not removed")
+	}
+}
+
+func TestHideSecretsKeepsInitFile(t *testing.T) {
+	secret := s3Config{keyID: "AKIA", secretKey: "hunter2"}.secretStatement()
+	saved := secretStatements
+	secretStatements = []string{secret}
+	t.Cleanup(func() { secretStatements = saved })
+
+	session := writeTestFile(t, t.TempDir(), "init.sql", ".maxwidth 80\n")
+	args, cleanup, err := hideSecrets([]string{"duckdb", "-init", session, "db.duckdb", "-c", secret + "SELECT 1;"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if slices.Contains(args, session) || slices.Index(args, "-init") != 1 || !slices.Equal(args[3:], []string{"db.duckdb", "-c", "SELECT 1;"}) {
+		t.Fatalf("args = %q, want a single init file", args)
+	}
+	if data, _ := os.ReadFile(args[2]); string(data) != secret+"\n.maxwidth 80\n\n" {
+		t.Errorf("init file = %q, want the secret followed by the session init file", data)
+	}
+}
+
+func TestHideSecretsWithoutSecret(t *testing.T) {
+	saved := secretStatements
+	secretStatements = []string{s3Config{region: "eu-west-1"}.secretStatement()}
+	t.Cleanup(func() { secretStatements = saved })
+
+	in := []string{"duckdb", "db.duckdb", "-c", "SELECT 1;"}
+	args, cleanup, err := hideSecrets(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	if !slices.Equal(args, in) {
+		t.Errorf("args = %q, want them unchanged", args)
+	}
+}
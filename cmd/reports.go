@@ -0,0 +1,592 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// checksumQuery hashes every row's text representation with MD5 and sums the two 64-bit halves
+// of the hashes. Sums do not depend on the row order and, unlike XOR, duplicate rows do not cancel
+// each other out. The row count and both sums are hashed again into the final checksum.
+var checksumQuery = fmt.Sprintf(`SELECT md5(count(*)::VARCHAR || ':' ||
+	coalesce(sum(md5_number_upper(%[1]s::VARCHAR)), 0)::VARCHAR || ':' ||
+	coalesce(sum(md5_number_lower(%[1]s::VARCHAR)), 0)::VARCHAR) FROM %[1]s;`, TableName)
+
+// printChecksum prints an order-independent checksum of the table contents
+func printChecksum(duckdbPath string) error {
+	checksum, err := queryScalar(duckdbPath, checksumQuery)
+	if err != nil {
+		return fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	fmt.Fprintln(os.Stdout, checksum)
+	return nil
+}
+
+// printDistinctCounts prints the total, distinct and duplicate row counts of the table
+func printDistinctCounts(duckdbPath string, outputArgs []string) error {
+	query := fmt.Sprintf(`SELECT total, "distinct", total - "distinct" AS duplicates FROM (
+	SELECT (SELECT count(*) FROM %[1]s) AS total,
+	       (SELECT count(*) FROM (SELECT DISTINCT * FROM %[1]s)) AS "distinct");`, TableName)
+	return runQuery(duckdbPath, query, outputArgs)
+}
+
+// nullReportQuery counts the NULLs of every column in a single scan, then unpivots the counts
+// into one row per column
+func nullReportQuery(columns []column) string {
+	counts := []string{"count(*) AS __dpi_total"}
+	for _, c := range columns {
+		counts = append(counts, fmt.Sprintf("count(*) FILTER (WHERE %[1]s IS NULL) AS %[1]s", quoteIdentifier(c.Name)))
+	}
+	return fmt.Sprintf(`WITH counts AS (SELECT %s FROM %s)
+SELECT "column", nulls, round(100.0 * nulls / nullif(__dpi_total, 0), 2) AS percent
+FROM (UNPIVOT counts ON COLUMNS(* EXCLUDE (__dpi_total)) INTO NAME "column" VALUE nulls);`,
+		strings.Join(counts, ", "), TableName)
+}
+
+// printNullReport prints the NULL count and percentage of every column
+func printNullReport(duckdbPath string, outputArgs []string) error {
+	columns, err := describeTable(duckdbPath)
+	if err != nil {
+		return err
+	}
+	return runQuery(duckdbPath, nullReportQuery(columns), outputArgs)
+}
+
+// uniqueReportLimit is the number of duplicated values shown by --unique
+const uniqueReportLimit = 10
+
+// checkUnique verifies that the column holds no duplicate values. When it does, the most frequent
+// duplicated values are printed and an error is returned so the exit code can be used in CI.
+// NULLs are grouped together, so more than one NULL also counts as a duplicate.
+func checkUnique(duckdbPath string, name string, outputArgs []string) error {
+	columns, err := describeTable(duckdbPath)
+	if err != nil {
+		return err
+	}
+	col, err := findColumn(columns, name)
+	if err != nil {
+		return err
+	}
+	ident := quoteIdentifier(col.Name)
+
+	count, err := queryScalar(duckdbPath, fmt.Sprintf(
+		"SELECT count(*) FROM (SELECT 1 FROM %s GROUP BY %s HAVING count(*) > 1);", TableName, ident))
+	if err != nil {
+		return err
+	}
+	if count == "0" {
+		fmt.Fprintf(os.Stdout, "Column '%s' is unique\n", col.Name)
+		return nil
+	}
+
+	fmt.Fprintf(os.Stdout, "Column '%s' has %s duplicated values, the most frequent are:\n", col.Name, count)
+	query := fmt.Sprintf("SELECT %[1]s, count(*) AS count FROM %[2]s GROUP BY %[1]s HAVING count(*) > 1 ORDER BY count DESC, %[1]s LIMIT %[3]d;",
+		ident, TableName, uniqueReportLimit)
+	if err := runQuery(duckdbPath, query, outputArgs); err != nil {
+		return err
+	}
+	return fmt.Errorf("column '%s' is not unique", col.Name)
+}
+
+// topReportLimit is the number of values shown by --top when no count is given
+const topReportLimit = 10
+
+// parseColumnSpec splits a flag value of the form column[:k], as taken by --top and --histogram, into
+// the column name and the count, def when it is not given. Only a numeric suffix is taken as k, so column
+// names containing a colon still work.
+func parseColumnSpec(flag string, spec string, def int) (string, int, error) {
+	name, k := spec, def
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		if n, err := strconv.Atoi(spec[i+1:]); err == nil {
+			if n < 1 {
+				return "", 0, fmt.Errorf("invalid --%s '%s': the number after the colon must be positive", flag, spec)
+			}
+			name, k = spec[:i], n
+		}
+	}
+	if name == "" {
+		return "", 0, fmt.Errorf("invalid --%s '%s': expected <column>[:k]", flag, spec)
+	}
+	return name, k, nil
+}
+
+// printTopValues prints the k most frequent values of the column with their counts and share of the rows
+func printTopValues(duckdbPath string, name string, k int, outputArgs []string) error {
+	columns, err := describeTable(duckdbPath)
+	if err != nil {
+		return err
+	}
+	col, err := findColumn(columns, name)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`SELECT %[1]s, count(*) AS count, round(100.0 * count(*) / sum(count(*)) OVER (), 2) AS percent
+FROM %[2]s GROUP BY %[1]s ORDER BY count DESC, %[1]s LIMIT %[3]d;`, quoteIdentifier(col.Name), TableName, k)
+	return runQuery(duckdbPath, query, outputArgs)
+}
+
+// printGroups runs the aggregate for every group of the columns and prints the groups with the largest
+// values first, only the first limit of them unless limit is 0. Groups with the same value are ordered
+// by their columns.
+func printGroups(duckdbPath string, names []string, agg string, limit int, outputArgs []string) error {
+	columns, err := describeTable(duckdbPath)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(names))
+	order := []string{strconv.Itoa(len(names)+1) + " DESC"}
+	for i, name := range names {
+		col, err := findColumn(columns, name)
+		if err != nil {
+			return fmt.Errorf("--group-by: %w", err)
+		}
+		keys = append(keys, quoteIdentifier(col.Name))
+		order = append(order, strconv.Itoa(i+1))
+	}
+	query := fmt.Sprintf("SELECT %[1]s, %[2]s FROM %[3]s GROUP BY %[1]s ORDER BY %[4]s",
+		strings.Join(keys, ", "), agg, TableName, strings.Join(order, ", "))
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return runQuery(duckdbPath, query+";", outputArgs)
+}
+
+// printMemoryReport prints the row and column counts of the tables and the size of the database
+// file. DuckDB caches the compressed storage blocks, so the size is also what holding the whole table
+// in memory takes.
+func printMemoryReport(duckdbPath string) error {
+	output, err := captureCommand([]string{"duckdb", duckdbPath, "-csv", "-noheader", "-c",
+		"SELECT table_name, estimated_size, column_count FROM duckdb_tables() WHERE schema_name = current_schema() ORDER BY table_name;"})
+	if err != nil {
+		return fmt.Errorf("failed to read table sizes: %w", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to parse table sizes: %w", err)
+	}
+
+	var size int64
+	for _, f := range []string{duckdbPath, duckdbPath + ".wal"} {
+		if info, err := os.Stat(f); err == nil {
+			size += info.Size()
+		}
+	}
+
+	for _, r := range records {
+		if len(r) != 3 {
+			return fmt.Errorf("unexpected table size row: %v", r)
+		}
+		rows, _ := strconv.ParseFloat(r[1], 64)
+		fmt.Fprintf(statusOut, "Table %s: %s rows, %s columns\n", r[0], formatCount(rows), r[2])
+	}
+	fmt.Fprintf(statusOut, "Database size: %s\n", formatBytes(size))
+	return nil
+}
+
+// printRowSummary prints the row count of the table to stderr. The table may have been dropped or
+// renamed during the session, which is reported instead of failing.
+func printRowSummary(duckdbPath string, tableName string) {
+	count, err := queryScalar(duckdbPath, fmt.Sprintf("SELECT count(*) FROM %s;", quoteIdentifier(tableName)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not count the rows of table %s: %v\n", tableName, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Loaded %s rows into table %s\n", count, tableName)
+}
+
+// rowCountBounds are the --min-rows and --max-rows-assert limits, a negative value meaning no limit
+type rowCountBounds struct {
+	min int64
+	max int64
+}
+
+// readRowCountBounds parses the --min-rows and --max-rows-assert flags
+func readRowCountBounds(cmd *cobra.Command) (rowCountBounds, error) {
+	bounds := rowCountBounds{min: -1, max: -1}
+	for _, b := range []struct {
+		flag  string
+		value *int64
+	}{{"min-rows", &bounds.min}, {"max-rows-assert", &bounds.max}} {
+		if !flagGiven(cmd, b.flag) {
+			continue
+		}
+		n, err := cmd.Flags().GetInt64(b.flag)
+		if err != nil || n < 0 {
+			return bounds, fmt.Errorf("--%s must be a positive number", b.flag)
+		}
+		*b.value = n
+	}
+	if bounds.min >= 0 && bounds.max >= 0 && bounds.min > bounds.max {
+		return bounds, fmt.Errorf("--min-rows %d is larger than --max-rows-assert %d", bounds.min, bounds.max)
+	}
+	return bounds, nil
+}
+
+// checkRowCount prints the row count of the table and returns an error if it is outside the bounds
+func checkRowCount(duckdbPath string, tableName string, bounds rowCountBounds) error {
+	result, err := queryScalar(duckdbPath, fmt.Sprintf("SELECT count(*) FROM %s;", quoteIdentifier(tableName)))
+	if err != nil {
+		return err
+	}
+	count, err := strconv.ParseInt(result, 10, 64)
+	if err != nil {
+		return fmt.Errorf("unexpected row count %q for table %s", result, tableName)
+	}
+	switch {
+	case bounds.min >= 0 && count < bounds.min:
+		return fmt.Errorf("table %s has %d row(s), fewer than --min-rows %d", tableName, count, bounds.min)
+	case bounds.max >= 0 && count > bounds.max:
+		return fmt.Errorf("table %s has %d row(s), more than --max-rows-assert %d", tableName, count, bounds.max)
+	}
+	fmt.Fprintf(os.Stdout, "Table %s has %d row(s)\n", tableName, count)
+	return nil
+}
+
+// printTypeReport prints the type every column of the table ended up with. VARCHAR columns whose values
+// mostly parse as numbers or dates are flagged, since a few dirty values make the CSV reader fall back to
+// VARCHAR for the whole column. All VARCHAR columns are checked in a single scan.
+func printTypeReport(duckdbPath string) error {
+	columns, err := describeTable(duckdbPath)
+	if err != nil {
+		return err
+	}
+
+	var checks []string
+	var varchars []column
+	for _, c := range columns {
+		if c.Type != "VARCHAR" {
+			continue
+		}
+		ident := quoteIdentifier(c.Name)
+		checks = append(checks, fmt.Sprintf("count(%[1]s), count(TRY_CAST(%[1]s AS DOUBLE)), count(TRY_CAST(%[1]s AS DATE))", ident))
+		varchars = append(varchars, c)
+	}
+
+	notes := make(map[string]string, len(varchars))
+	if len(checks) > 0 {
+		result, err := queryScalar(duckdbPath, fmt.Sprintf("SELECT %s FROM %s;", strings.Join(checks, ", "), TableName))
+		if err != nil {
+			return fmt.Errorf("failed to check VARCHAR columns: %w", err)
+		}
+		counts := strings.Split(result, ",")
+		if len(counts) != 3*len(varchars) {
+			return fmt.Errorf("unexpected result of the VARCHAR column check: %s", result)
+		}
+		for i, c := range varchars {
+			values, _ := strconv.ParseInt(counts[3*i], 10, 64)
+			numbers, _ := strconv.ParseInt(counts[3*i+1], 10, 64)
+			dates, _ := strconv.ParseInt(counts[3*i+2], 10, 64)
+			notes[c.Name] = varcharNote(values, numbers, dates)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COLUMN\tTYPE\tNOTE")
+	for _, c := range columns {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Type, notes[c.Name])
+	}
+	return w.Flush()
+}
+
+// typeReportThreshold is the share of values that must look like numbers or dates for a VARCHAR column
+// to be flagged
+const typeReportThreshold = 0.9
+
+// varcharNote describes a VARCHAR column whose values mostly look like numbers or dates
+func varcharNote(values, numbers, dates int64) string {
+	if values == 0 {
+		return ""
+	}
+	kind, matching := "numbers", numbers
+	if dates > numbers {
+		kind, matching = "dates", dates
+	}
+	if float64(matching) < typeReportThreshold*float64(values) {
+		return ""
+	}
+	if matching == values {
+		return fmt.Sprintf("all %d values look like %s", values, kind)
+	}
+	return fmt.Sprintf("%d of %d values (%.1f%%) look like %s", matching, values, 100*float64(matching)/float64(values), kind)
+}
+
+// printDistinctCount prints the number of distinct non-NULL values of the column, estimated with
+// approx_count_distinct when approx is set
+func printDistinctCount(duckdbPath string, name string, approx bool) error {
+	columns, err := describeTable(duckdbPath)
+	if err != nil {
+		return err
+	}
+	col, err := findColumn(columns, name)
+	if err != nil {
+		return err
+	}
+	expr := fmt.Sprintf("count(DISTINCT %s)", quoteIdentifier(col.Name))
+	if approx {
+		expr = fmt.Sprintf("approx_count_distinct(%s)", quoteIdentifier(col.Name))
+	}
+	count, err := queryScalar(duckdbPath, fmt.Sprintf("SELECT %s FROM %s;", expr, TableName))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, count)
+	return nil
+}
+
+// printMinMax prints the minimum and maximum of the column without loading the table. For Parquet files
+// they are taken from the row group statistics when possible, which needs no scan of the data.
+func printMinMax(input inputTable, name string) error {
+	setup := sessionSetup(input.fileFormat, input.opts)
+	query, err := buildSelectQuery(input.filename(), input.fileFormat, input.opts)
+	if err != nil {
+		return err
+	}
+	columns, err := describeQuery(setup, query)
+	if err != nil {
+		return err
+	}
+	col, err := findColumn(columns, name)
+	if err != nil {
+		return err
+	}
+
+	if input.fileFormat == Parquet && statsMatchTable(input.opts) && hasExactStats(col.Type) {
+		lo, hi, ok, err := parquetStatsRange(setup, input.files, col)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fmt.Fprintln(statusOut, "Read from the Parquet statistics, no data was scanned")
+			printRange(lo, hi)
+			return nil
+		}
+	}
+
+	ident := quoteIdentifier(col.Name)
+	output, err := captureCommand([]string{"duckdb", "-csv", "-noheader", "-c", loadSettings(input.opts) + setup +
+		fmt.Sprintf("SELECT min(%[1]s)::VARCHAR, max(%[1]s)::VARCHAR FROM (%[2]s);", ident, query)})
+	if err != nil {
+		return fmt.Errorf("failed to compute the range of %s: %w", col.Name, err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil || len(records) != 1 || len(records[0]) != 2 {
+		return fmt.Errorf("unexpected output for the range of %s: %q", col.Name, output)
+	}
+	printRange(records[0][0], records[0][1])
+	return nil
+}
+
+// statsMatchTable reports whether the values of the table are those stored in the files, so their
+// statistics apply. Options that rename, cast or drop rows change them.
+func statsMatchTable(opts tableOptions) bool {
+	return !opts.allVarchar && !opts.lowercaseColumns && len(opts.renames) == 0 && len(opts.partitionFilter) == 0 &&
+		opts.where == "" && opts.limitPerFile == 0 && opts.sample == ""
+}
+
+// hasExactStats reports whether Parquet writers store exact statistics for the type. Strings and
+// binary values may have their statistics truncated.
+func hasExactStats(typ string) bool {
+	t, err := parseDuckType(typ)
+	if err != nil {
+		return false
+	}
+	switch t.Name {
+	case "DATE", "TIME", "TIMESTAMP", "TIMESTAMP WITH TIME ZONE", "TIMESTAMPTZ", "TIMESTAMP_S", "TIMESTAMP_MS", "TIMESTAMP_NS":
+		return true
+	default:
+		return t.isNumeric()
+	}
+}
+
+// printRange prints a minimum and maximum read as CSV fields, where NULL is an empty field
+func printRange(lo string, hi string) {
+	if lo == "" && hi == "" {
+		lo, hi = "NULL", "NULL"
+	}
+	fmt.Fprintf(os.Stdout, "min: %s\nmax: %s\n", lo, hi)
+}
+
+// printValueSearch prints the columns where at least one value equals the search value, or matches it
+// as a regular expression with regex, with the number of such rows. Values are compared in their text
+// form, so numbers and dates are found as they are printed. All columns are searched in one scan.
+func printValueSearch(duckdbPath string, value string, regex bool) error {
+	columns, err := describeTable(duckdbPath)
+	if err != nil {
+		return err
+	}
+	counts := make([]string, 0, len(columns))
+	for _, c := range columns {
+		condition := fmt.Sprintf("%s::VARCHAR = %s", quoteIdentifier(c.Name), quoteLiteral(value))
+		if regex {
+			condition = fmt.Sprintf("regexp_matches(%s::VARCHAR, %s)", quoteIdentifier(c.Name), quoteLiteral(value))
+		}
+		counts = append(counts, fmt.Sprintf("count(*) FILTER (WHERE %s)", condition))
+	}
+	result, err := queryScalar(duckdbPath, fmt.Sprintf("SELECT %s FROM %s;", strings.Join(counts, ", "), TableName))
+	if err != nil {
+		return fmt.Errorf("failed to search the columns: %w", err)
+	}
+	fields := strings.Split(result, ",")
+	if len(fields) != len(columns) {
+		return fmt.Errorf("unexpected search result: %q", result)
+	}
+
+	type match struct {
+		name  string
+		count int64
+	}
+	var matches []match
+	for i, f := range fields {
+		if n, err := strconv.ParseInt(f, 10, 64); err == nil && n > 0 {
+			matches = append(matches, match{columns[i].Name, n})
+		}
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(os.Stdout, "No column contains '%s'\n", value)
+		return nil
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].count > matches[j].count })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COLUMN\tMATCHING ROWS")
+	for _, m := range matches {
+		fmt.Fprintf(w, "%s\t%d\n", m.name, m.count)
+	}
+	return w.Flush()
+}
+
+// printCorrelation prints the Pearson correlation coefficient of two numeric columns and the number
+// of rows where both are set, which are the pairs it is computed from
+func printCorrelation(duckdbPath string, spec string) error {
+	names := strings.Split(spec, ",")
+	if len(names) != 2 || strings.TrimSpace(names[0]) == "" || strings.TrimSpace(names[1]) == "" {
+		return fmt.Errorf("invalid --corr '%s': expected <column1>,<column2>", spec)
+	}
+	columns, err := describeTable(duckdbPath)
+	if err != nil {
+		return err
+	}
+	var idents []string
+	var cols []column
+	for _, name := range names {
+		col, err := findColumn(columns, strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		if t, err := parseDuckType(col.Type); err != nil || !t.isNumeric() {
+			return fmt.Errorf("--corr needs numeric columns, '%s' is %s", col.Name, col.Type)
+		}
+		idents = append(idents, quoteIdentifier(col.Name))
+		cols = append(cols, col)
+	}
+
+	result, err := queryScalar(duckdbPath, fmt.Sprintf(
+		"SELECT corr(%[1]s, %[2]s), count(*) FILTER (WHERE %[1]s IS NOT NULL AND %[2]s IS NOT NULL) FROM %[3]s;",
+		idents[0], idents[1], TableName))
+	if err != nil {
+		return fmt.Errorf("failed to compute the correlation: %w", err)
+	}
+	coefficient, pairs, _ := strings.Cut(result, ",")
+	// corr() is NULL or NaN with fewer than two pairs or when a column is constant
+	if f, err := strconv.ParseFloat(coefficient, 64); err != nil || math.IsNaN(f) {
+		coefficient = "undefined"
+	} else {
+		coefficient = strconv.FormatFloat(f, 'f', 4, 64)
+	}
+	fmt.Fprintf(os.Stdout, "corr(%s, %s) = %s over %s non-null pairs\n", cols[0].Name, cols[1].Name, coefficient, pairs)
+	return nil
+}
+
+// histogramBuckets is the number of buckets of --histogram when none is given
+const histogramBuckets = 10
+
+// histogramBarWidth is the length of the bar of the fullest bucket
+const histogramBarWidth = 40
+
+// histogramQuery splits the range of the column into equal-width buckets and counts the values of
+// each. The maximum falls into the last bucket, and a column with a single distinct value into the first.
+const histogramQuery = `WITH bounds AS (SELECT min(%[1]s)::DOUBLE AS lo, max(%[1]s)::DOUBLE AS hi FROM %[2]s)
+SELECT CASE WHEN hi = lo THEN 0 ELSE least(floor((%[1]s::DOUBLE - lo) / (hi - lo) * %[3]d)::BIGINT, %[3]d - 1) END AS bucket,
+	count(*), any_value(lo), any_value(hi)
+FROM %[2]s, bounds WHERE %[1]s IS NOT NULL GROUP BY bucket ORDER BY bucket;`
+
+// printHistogram prints an ASCII bar chart of the distribution of a numeric column over equal-width buckets
+func printHistogram(duckdbPath string, name string, buckets int) error {
+	columns, err := describeTable(duckdbPath)
+	if err != nil {
+		return err
+	}
+	col, err := findColumn(columns, name)
+	if err != nil {
+		return err
+	}
+	if t, err := parseDuckType(col.Type); err != nil || !t.isNumeric() {
+		return fmt.Errorf("--histogram needs a numeric column, '%s' is %s", col.Name, col.Type)
+	}
+
+	output, err := captureCommand([]string{"duckdb", duckdbPath, "-csv", "-noheader", "-c",
+		fmt.Sprintf(histogramQuery, quoteIdentifier(col.Name), TableName, buckets)})
+	if err != nil {
+		return fmt.Errorf("failed to compute the histogram: %w", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to parse the histogram: %w", err)
+	}
+	if len(records) == 0 {
+		fmt.Fprintf(os.Stdout, "Column '%s' has no values\n", col.Name)
+		return nil
+	}
+
+	counts := make([]int64, buckets)
+	var lo, hi float64
+	var largest int64
+	for _, r := range records {
+		if len(r) != 4 {
+			return fmt.Errorf("unexpected histogram row: %v", r)
+		}
+		bucket, _ := strconv.Atoi(r[0])
+		count, _ := strconv.ParseInt(r[1], 10, 64)
+		lo, _ = strconv.ParseFloat(r[2], 64)
+		hi, _ = strconv.ParseFloat(r[3], 64)
+		if bucket >= 0 && bucket < buckets {
+			counts[bucket] = count
+			largest = max(largest, count)
+		}
+	}
+	if lo == hi {
+		buckets, counts = 1, counts[:1]
+	}
+
+	// Lines like "  0 - 19.8  60 ####", with the bounds and counts aligned
+	width := (hi - lo) / float64(buckets)
+	froms, tos, counted := make([]string, buckets), make([]string, buckets), make([]string, buckets)
+	var fromWidth, toWidth, countWidth int
+	for i, count := range counts {
+		to := lo + float64(i+1)*width
+		if i == buckets-1 {
+			to = hi
+		}
+		froms[i], tos[i], counted[i] = formatBucketBound(lo+float64(i)*width), formatBucketBound(to), strconv.FormatInt(count, 10)
+		fromWidth, toWidth, countWidth = max(fromWidth, len(froms[i])), max(toWidth, len(tos[i])), max(countWidth, len(counted[i]))
+	}
+	for i, count := range counts {
+		bar := strings.Repeat("#", int(float64(histogramBarWidth)*float64(count)/float64(largest)+0.5))
+		fmt.Fprintf(os.Stdout, "%*s - %-*s %*s %s\n", fromWidth, froms[i], toWidth, tos[i], countWidth, counted[i], bar)
+	}
+	return nil
+}
+
+// formatBucketBound prints a bucket boundary compactly, without trailing zeros
+func formatBucketBound(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
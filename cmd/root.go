@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -18,6 +24,10 @@ type FileFormat string
 const (
 	Parquet FileFormat = "parquet"
 	CSV     FileFormat = "csv"
+	JSON    FileFormat = "json"
+	Arrow   FileFormat = "arrow"
+	Delta   FileFormat = "delta"
+	Iceberg FileFormat = "iceberg"
 )
 
 const TableName = "p" // p for preview
@@ -25,38 +35,168 @@ const TableName = "p" // p for preview
 // FileNameString represents one or more file names enclosed in single quotes and separated by commas
 type FileNameString string
 
-func exitWithError(format string, args ...any) {
-	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
-	os.Exit(1)
-}
+// statusOut receives the setup progress messages. Modes whose output is meant to be piped
+// send them to stderr so stdout only carries the result.
+var statusOut io.Writer = os.Stdout
 
 const version = "1.0.0"
 
 var rootCmd = &cobra.Command{
-	Use:     "dpi <file or pattern>",
-	Short:   "DuckDB Parquet/CSV Inspector",
+	Use:     "dpi <file or pattern>...",
+	Short:   "DuckDB Parquet/CSV/Delta/Iceberg Inspector",
 	Version: version,
-	Long:    `DPI is a tool for inspecting Parquet and CSV files using DuckDB.`,
+	Long:    `DPI is a tool for inspecting Parquet, CSV, JSON and Arrow IPC files and Delta Lake and Iceberg tables using DuckDB.`,
 	Example: `  dpi data.parquet
   dpi *.parquet
   dpi data.csv
   dpi -s data.csv          # With strict mode for CSV
   dpi -a data.parquet      # Read all columns as VARCHAR
-  dpi -a -s data.csv       # Combined flags`,
-	Args: cobra.ExactArgs(1),
-	Run:  runCommand,
+  dpi -a -s data.csv       # Combined flags
+  dpi -l data.parquet      # Lowercase all column names
+  dpi --run nulls data.csv # Run a saved snippet and exit
+  dpi -e 'SELECT count(*) FROM p' data.parquet
+  dpi -e 'SELECT * FROM p LIMIT 1' --transpose data.csv
+  dpi --dialect prql -e 'from p | take 5' data.csv
+  dpi --schema --schema-format json-schema data.parquet
+  dpi --require-columns id,amount data.csv # Fail unless the columns exist
+  dpi --min-rows 1 --max-rows-assert 1000000 out.parquet   # Fail on empty or bloated outputs
+  dpi --partition-filter year=2024,month=01 'lake/*/*/*.parquet'
+  dpi --version-as-of 3 path/to/delta_table
+  dpi --snapshot 4183020680887155442 path/to/iceberg_table
+  dpi --as-of '2024-06-01 12:00:00' path/to/iceberg_table
+  dpi --checksum data.parquet
+  dpi --limit-bytes 100MB huge.csv
+  dpi --fast huge.csv      # Skip type detection for a quick first look
+  dpi --distinct data.parquet              # Count duplicate rows
+  dpi --nulls data.csv                     # NULL counts per column
+  dpi --unique id data.parquet             # Fail if id has duplicates
+  dpi --top country:5 data.csv             # Five most frequent countries
+  dpi --histogram amount data.parquet      # Distribution of a numeric column
+  dpi --corr price,quantity data.parquet   # Correlation of two numeric columns
+  dpi --count-distinct customer data.csv   # Number of unique values of a column
+  dpi --find 'ORD-1234' data.parquet       # Columns containing a value
+  dpi --min-max created_at data.parquet    # Range of a column, from the Parquet statistics
+  dpi --run-file report.sql --output-format csv data.parquet > report.csv
+  dpi --row-groups data.parquet            # Row group sizes and encodings
+  dpi --parquet-schema data.parquet        # Physical Parquet types and repetition
+  dpi --print-sql -a data.csv              # The CREATE TABLE statement dpi would run
+  dpi orders.csv customers.parquet         # One table per file: orders, customers
+  dpi --start-query 'SUMMARIZE p' data.parquet
+  dpi --database sales.duckdb sales.csv    # Keep the table for later sessions
+  dpi sales.duckdb                         # Open an existing DuckDB database read-only
+  dpi --per-file --keep-going -e 'SELECT count(*) FROM p' '*.parquet'`,
+	Args: cobra.MinimumNArgs(1),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		applyErrorFormat(cmd)
+		if err := dropConflictingDefaults(cmd); err != nil {
+			return err
+		}
+		requireDuckDB(cmd, args)
+		return nil
+	},
+	Run: runCommand,
 }
 
 func init() {
-	rootCmd.Flags().BoolP("strict", "s", false, "Enable strict mode (for CSV files)")
-	rootCmd.Flags().BoolP("all-varchar", "a", false, "Read all columns as VARCHAR (disable type detection)")
+	rootCmd.PersistentFlags().Var(&errorFormat, "error-format", "Format of the error messages on stderr: text, or json for {\"error\": ..., \"code\": N} objects")
+	addReadFlags(rootCmd)
+	rootCmd.Flags().String("run", "", "Run the named snippet against the table and exit (see 'dpi snippets')")
+	rootCmd.Flags().Int("width", 0, "Maximum width of the rendered tables (default: terminal width)")
+	rootCmd.Flags().StringP("exec", "e", "", "Run the SQL against the table and exit instead of starting the DuckDB CLI")
+	rootCmd.Flags().String("dialect", string(DialectSQL), "Language of the --exec query: sql, or prql to write PRQL through DuckDB's prql extension")
+	rootCmd.Flags().String("run-file", "", "Run the SQL script against the table and exit with DuckDB's exit code")
+	rootCmd.Flags().String("output-format", string(OutputDuckbox), "Output format of query results: duckbox, box, csv, json, ndjson, line, list, markdown or html")
+	rootCmd.Flags().String("database", "", "Create the table in this DuckDB database file and keep it instead of using a temporary one")
+	rootCmd.Flags().Bool("force", false, "With --database, replace an existing table without asking")
+	rootCmd.Flags().String("start-query", "", "Run the SQL and print its result before starting the DuckDB CLI")
+	rootCmd.Flags().String("prompt", "", "Prompt of the DuckDB CLI (default: the base name of the first input)")
+	rootCmd.Flags().Bool("no-banner", false, "Do not print the table names, column and row counts when the DuckDB CLI starts")
+	rootCmd.Flags().Bool("summary-on-exit", false, "Print the row count of the table when the DuckDB CLI exits")
+	rootCmd.Flags().Bool("union", false, "Load the files matched by all Parquet patterns into the single table p instead of one table per argument")
+	rootCmd.Flags().String("prepend-sql", "", "SQL statements to run before the --exec query in the same DuckDB invocation, e.g. SET statements")
+	rootCmd.Flags().String("append-sql", "", "SQL statements to run after the --exec query in the same DuckDB invocation, skipped when it fails")
+	rootCmd.Flags().Bool("per-file", false, "Run --exec against every matched file separately instead of their union")
+	rootCmd.Flags().String("concat-output", "", "With --per-file, append the results of all files with a source column to one CSV or NDJSON file, - for stdout")
+	rootCmd.Flags().Bool("keep-going", false, "With --per-file, continue with the next file when one fails")
+	rootCmd.Flags().Int("parallel", defaultParallel(), "With --per-file, the number of files loaded at once, their queries run in file order")
+	rootCmd.Flags().Bool("transpose", false, "Print each result row as a column = value listing (for --exec, --run and --run-file)")
+	rootCmd.Flags().Bool("schema", false, "Print the schema and exit without creating the table")
+	rootCmd.Flags().String("require-columns", "", "Check that the input has all the given comma separated columns and exit, non-zero if any is missing")
+	rootCmd.Flags().Int64("min-rows", 0, "Check that every table has at least N rows and exit, non-zero if one has fewer")
+	rootCmd.Flags().Int64("max-rows-assert", 0, "Check that every table has at most N rows and exit, non-zero if one has more")
+	rootCmd.Flags().Bool("structure", false, "Print the nested fields of the columns as an indented tree and exit")
+	rootCmd.Flags().Bool("ddl", false, "Print a CREATE TABLE statement with the column types of the table and exit")
+	rootCmd.Flags().String("schema-format", string(SchemaDuckDB), "Schema output format for --schema: duckdb, arrow or json-schema")
+	rootCmd.Flags().Bool("checksum", false, "Print an order-independent checksum of the data and exit")
+	rootCmd.Flags().Bool("nulls", false, "Print the NULL count and percentage of every column and exit")
+	rootCmd.Flags().String("unique", "", "Check that the column has no duplicate values and exit, non-zero if it does")
+	rootCmd.Flags().String("top", "", "Print the most frequent values of the column, as <column>[:k] (default k is 10), and exit")
+	rootCmd.Flags().String("count-distinct", "", "Print the number of distinct values of the column and exit")
+	rootCmd.Flags().Bool("approx", false, "With --count-distinct, estimate the count with HyperLogLog, which is faster on large data")
+	rootCmd.Flags().String("group-by", "", "Print an aggregate per group of the comma separated columns, largest first, and exit")
+	rootCmd.Flags().String("agg", "count(*)", "Aggregate expression --group-by computes for every group, e.g. 'sum(amount)'")
+	rootCmd.Flags().Int("limit", 0, "With --group-by, only print the N largest groups")
+	rootCmd.Flags().String("min-max", "", "Print the minimum and maximum of the column and exit, from the Parquet statistics when possible")
+	rootCmd.Flags().String("find", "", "Print the columns containing the value with their number of matching rows, and exit")
+	rootCmd.Flags().Bool("regex", false, "With --find, match the values against a regular expression instead")
+	rootCmd.Flags().String("corr", "", "Print the correlation coefficient of two numeric columns, as <column1>,<column2>, and exit")
+	rootCmd.Flags().String("histogram", "", "Print a bar chart of a numeric column over <column>[:buckets] equal-width buckets (default 10), and exit")
+	rootCmd.Flags().Bool("follow", false, "Print the rows appended to a local CSV file as it grows, like tail -f, until interrupted")
+	rootCmd.Flags().Bool("explain-pruning", false, "Print the filters of --where pushed into the Parquet scan and the number of files read, and exit")
+	rootCmd.Flags().Bool("parquet-schema", false, "Print the physical schema of the Parquet files and exit")
+	rootCmd.Flags().Bool("estimate", false, "Estimate the size of the table before loading it and ask before exceeding the free disk space")
+	rootCmd.Flags().Bool("mem-report", false, "Print the row counts and storage size of the table after loading it")
+	rootCmd.Flags().Bool("print-sql", false, "Print the SQL that creates the table and exit, without any other output")
+	rootCmd.Flags().Bool("type-report", false, "Print the type of every CSV column and flag VARCHAR columns holding numbers or dates, and exit")
+	rootCmd.Flags().Bool("row-groups", false, "Print the row groups of the Parquet files with their sizes and encodings and exit")
+	rootCmd.Flags().Bool("distinct", false, "Print total, distinct and duplicate row counts and exit, or with --exec only print distinct result rows")
+	rootCmd.MarkFlagsMutuallyExclusive(batchModeFlags...)
+	for _, name := range batchModeFlags {
+		if name != "exec" {
+			rootCmd.MarkFlagsMutuallyExclusive("distinct", name)
+		}
+		// Both row count bounds make up a single check
+		if name != "min-rows" {
+			rootCmd.MarkFlagsMutuallyExclusive("max-rows-assert", name)
+		}
+	}
+	rootCmd.MarkFlagsMutuallyExclusive("distinct", "max-rows-assert")
+	rootCmd.MarkFlagsMutuallyExclusive("transpose", "output-format")
+	rootCmd.MarkFlagsMutuallyExclusive("database", "per-file")
+	rootCmd.MarkFlagsMutuallyExclusive("union", "range")
 }
 
-func Execute() {
-	// check if DuckDB binary is available
+// batchModeFlags are the flags that print a result and exit instead of starting the DuckDB CLI
+var batchModeFlags = []string{"exec", "run", "run-file", "schema", "structure", "ddl", "require-columns", "min-rows", "checksum", "nulls", "unique", "top", "group-by", "count-distinct", "min-max", "find", "histogram", "corr", "type-report", "row-groups", "explain-pruning", "follow", "parquet-schema", "print-sql"}
+
+// multiInputFlags are the batchModeFlags that also work with several inputs, the others report on a single table
+var multiInputFlags = map[string]bool{"exec": true, "run": true, "run-file": true, "print-sql": true, "ddl": true, "require-columns": true, "min-rows": true}
+
+// isBatchMode reports whether one of the batchModeFlags was given. --max-rows-assert is left out of
+// them since it may be combined with --min-rows, but it still prints a result and exits.
+func isBatchMode(cmd *cobra.Command) bool {
+	for _, name := range batchModeFlags {
+		if flagGiven(cmd, name) {
+			return true
+		}
+	}
+	return flagGiven(cmd, "max-rows-assert")
+}
+
+// requireDuckDB checks that the DuckDB binary is available before a command runs.
+// Commands that can run without DuckDB override it with their own PersistentPreRunE.
+func requireDuckDB(cmd *cobra.Command, args []string) {
 	if err := ensureDuckDBBinary(); err != nil {
 		exitWithError("%v", err)
 	}
+}
+
+func Execute() {
+	// Flags of every file's init() are registered by now, so the environment can be applied
+	if err := applyEnvironment(rootCmd); err != nil {
+		exitWithError("%v", err)
+	}
+	formatEarlyErrors(rootCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		exitWithError("Command execution failed: %v", err)
@@ -68,47 +208,405 @@ func fileExists(filename string) bool {
 	return !os.IsNotExist(err)
 }
 
-func createTempDirectory() (string, error) {
-	return os.MkdirTemp("", "dpi")
+func determineFileFormat(filename string) FileFormat {
+	if isDeltaTable(filename) {
+		return Delta
+	}
+	if isIcebergTable(filename) {
+		return Iceberg
+	}
+	return formatByExtension(filename)
 }
 
-func determineFileFormat(filename string) FileFormat {
+// formatByExtension returns the file format the extension of the name stands for, without looking at
+// the file system, or "" when it names no supported file format
+func formatByExtension(filename string) FileFormat {
+	// Compressed files are told apart by the extension of their contents, e.g. data.csv.zst
+	if compressionSuffix(filename) != "" {
+		switch strings.ToLower(filepath.Ext(stripCompressionSuffix(filename))) {
+		case ".parquet":
+			return "" // Parquet compresses its pages itself, DuckDB cannot read compressed files
+		case ".arrow", ".feather", ".ipc":
+			return "" // Arrow IPC files compress their buffers themselves
+		case ".json", ".ndjson", ".jsonl":
+			return JSON
+		default:
+			return CSV
+		}
+	}
+
 	ext := filepath.Ext(filename)
 	switch strings.ToLower(ext) {
 	case ".parquet":
 		return Parquet
-	case ".csv", ".gz":
+	case ".csv", ".txt":
 		return CSV
+	case ".json", ".ndjson", ".jsonl":
+		return JSON
+	case ".arrow", ".feather", ".ipc":
+		return Arrow
 	default:
 		return "" // Unsupported format
 	}
 }
 
-func createTemporaryTable(filename FileNameString, tempDir string, fileFormat FileFormat, strict bool, allVarchar bool) error {
-	var query string
+// isEmptyFile reports whether the path is a regular file of zero bytes
+func isEmptyFile(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && info.Mode().IsRegular() && info.Size() == 0
+}
+
+// parseFileFormat parses the --format flag, which overrides the detection
+func parseFileFormat(s string) (FileFormat, error) {
+	switch f := FileFormat(strings.ToLower(s)); f {
+	case Parquet, CSV, JSON, Arrow, Delta, Iceberg:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported format '%s' (expected %s, %s, %s, %s, %s or %s)", s, Parquet, CSV, JSON, Arrow, Delta, Iceberg)
+	}
+}
+
+// tableOptions holds the flags that affect how the input files are read into the table
+type tableOptions struct {
+	strict            bool
+	allVarchar        bool
+	lowercaseColumns  bool
+	renames           map[string]string // old to new column names from --rename-map
+	columns           []string          // columns to load from --columns, empty for all
+	limitColumns      int               // load only the first N columns, 0 for all
+	excludeColumns    []string          // columns left out by --exclude-columns
+	partitionFilter   []partitionPredicate
+	where             string   // SQL condition on the columns of the files from --where
+	versionAsOf       string   // Delta table version to read, empty for the latest
+	snapshot          string   // Iceberg snapshot id to read, empty for the current snapshot
+	snapshotTimestamp string   // read the Iceberg snapshot current at this timestamp, empty for the current snapshot
+	fastColumns       []string // CSV header for --fast, which reads every column as VARCHAR without detection
+	columnTypes       []column // CSV column types for --follow, so new rows keep the types of the whole file
+	delim             string   // CSV delimiter from --delim or content sniffing, empty for auto-detection
+	dateFormat        string   // strftime format of CSV DATE values, empty for auto-detection
+	timestampFormat   string   // strftime format of CSV TIMESTAMP values, empty for auto-detection
+	maxLineSize       int64    // longest CSV line DuckDB accepts in bytes, 0 for DuckDB's default
+	sampleSize        int64    // CSV rows sampled for type detection, 0 for DuckDB's default and -1 for all
+	noHeader          bool     // read the first CSV line as data
+	compression       string   // read_csv or read_json compression for files DuckDB decompresses, empty for none
+	withFilename      bool     // add the filename column of the read function
+	filenamePrefix    string   // directory prefix stripped from the filename column for --relative-paths
+	limitPerFile      int      // rows to read from every file, 0 to read everything
+	trim              bool     // strip leading and trailing spaces from the VARCHAR columns of a CSV
+	flattenDepth      int      // levels of JSON structs expanded into dotted columns, 0 to keep them nested
+	sample            string   // DuckDB sample clause from --sample, empty to load every row
+	sampleSeed        string   // setseed value making the sample repeatable, empty for a random sample
+	rownum            bool     // prepend a row number column named rn
+	force             bool     // replace existing tables in a --database without asking
+	remote            bool     // the input is a URL read through the httpfs extension
+	deterministic     bool     // load single-threaded in file order so repeated runs give the same row order
+	noAutoload        bool     // keep DuckDB from installing and loading known extensions by itself
+	utc               bool     // read and print TIMESTAMPTZ values in UTC
+	settings          string   // SET statements from --set
+	unionByName       bool     // match the columns of the files by name instead of by position
+	randomFile        bool     // load a single file picked from those the pattern matches
+	s3                s3Config // credentials for S3 URLs from the --s3-* flags
+}
+
+// buildSelectQuery returns the SELECT statement used to populate the temporary table
+func buildSelectQuery(filename FileNameString, fileFormat FileFormat, opts tableOptions) (string, error) {
+	selectList := "*"
+	var params []string // named parameters passed to the read function
 
 	switch fileFormat {
 	case Parquet:
-		if allVarchar {
-			query = fmt.Sprintf(`CREATE TABLE %s AS SELECT COLUMNS(*):VARCHAR FROM read_parquet([%s]);`,
-				TableName, filename)
-		} else {
-			query = fmt.Sprintf(`CREATE TABLE %s AS SELECT * FROM read_parquet([%s]);`,
-				TableName, filename)
+		if opts.allVarchar {
+			selectList = "COLUMNS(*):VARCHAR"
+		}
+	case Delta:
+		if opts.allVarchar {
+			selectList = "COLUMNS(*):VARCHAR"
+		}
+		if opts.versionAsOf != "" {
+			params = append(params, "version="+opts.versionAsOf)
+		}
+	case Iceberg:
+		if opts.allVarchar {
+			selectList = "COLUMNS(*):VARCHAR"
+		}
+		if opts.snapshot != "" {
+			params = append(params, "snapshot_from_id="+opts.snapshot)
+		}
+		if opts.snapshotTimestamp != "" {
+			params = append(params, "snapshot_from_timestamp=TIMESTAMP "+quoteLiteral(opts.snapshotTimestamp))
 		}
 	case CSV:
-		if allVarchar {
-			query = fmt.Sprintf(`CREATE TABLE %s AS SELECT * FROM read_csv(%s, strict_mode=%v, all_varchar=true);`,
-				TableName, filename, strict)
-		} else {
-			query = fmt.Sprintf(`CREATE TABLE %s AS SELECT * FROM read_csv(%s, strict_mode=%v);`,
-				TableName, filename, strict)
+		params = append(params, csvReadParams(opts)...)
+	case Arrow:
+		if opts.allVarchar {
+			selectList = "COLUMNS(*)::VARCHAR"
+		}
+	case JSON:
+		if opts.compression != "" {
+			params = append(params, "compression="+quoteLiteral(opts.compression))
+		}
+		if opts.allVarchar {
+			selectList = "COLUMNS(*)::VARCHAR"
 		}
 	default:
-		return fmt.Errorf("unsupported file format: %s", fileFormat)
+		return "", fmt.Errorf("unsupported file format: %s", fileFormat)
 	}
 
-	duckdbPath := filepath.Join(tempDir, "tmp.duckdb")
+	if opts.unionByName {
+		params = append(params, "union_by_name=true")
+	}
+	if len(opts.partitionFilter) > 0 {
+		params = append(params, "hive_partitioning=true")
+	}
+	// --limit-per-file numbers the rows of every file, which needs the filename column
+	if opts.withFilename || opts.limitPerFile > 0 {
+		params = append(params, "filename=true")
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s`, selectList, readFunction(filename, fileFormat, params))
+	if where := whereClause(opts); where != "" {
+		query += " WHERE " + where
+	}
+	if opts.limitPerFile > 0 {
+		query += fmt.Sprintf(" QUALIFY row_number() OVER (PARTITION BY filename) <= %d", opts.limitPerFile)
+		if !opts.withFilename {
+			query = fmt.Sprintf(`SELECT * EXCLUDE (filename) FROM (%s)`, query)
+		}
+	}
+	if opts.filenamePrefix != "" {
+		query = fmt.Sprintf(`SELECT * REPLACE (substr(filename, %d) AS filename) FROM (%s)`, len(opts.filenamePrefix)+1, query)
+	}
+	if opts.sample != "" {
+		query = fmt.Sprintf(`SELECT * FROM (%s) USING SAMPLE %s`, query, opts.sample)
+	}
+	if opts.trim {
+		columns, err := describeQuery(sessionSetup(fileFormat, opts), query)
+		if err != nil {
+			return "", err
+		}
+		if selectList := trimSelectList(columns); selectList != "*" {
+			query = fmt.Sprintf(`SELECT %s FROM (%s)`, selectList, query)
+		}
+	}
+	if opts.flattenDepth > 0 {
+		columns, err := describeQuery(sessionSetup(fileFormat, opts), query)
+		if err != nil {
+			return "", err
+		}
+		selectList, err := flattenSelectList(columns, opts.flattenDepth)
+		if err != nil {
+			return "", err
+		}
+		query = fmt.Sprintf(`SELECT %s FROM (%s)`, selectList, query)
+	}
+
+	if len(opts.columns) > 0 || opts.limitColumns > 0 {
+		columns, err := describeQuery(sessionSetup(fileFormat, opts), query)
+		if err != nil {
+			return "", err
+		}
+		selectList, err := projectionSelectList(columns, opts.columns, opts.limitColumns, opts.withFilename)
+		if err != nil {
+			return "", err
+		}
+		total := len(columns)
+		if opts.withFilename {
+			total-- // the filename column is always kept
+		}
+		if opts.limitColumns > 0 && opts.limitColumns < total {
+			fmt.Fprintf(statusOut, "Note: only loading the first %d of %d columns (--limit-columns)\n", opts.limitColumns, total)
+		}
+		query = fmt.Sprintf(`SELECT %s FROM (%s)`, selectList, query)
+	}
+	if len(opts.excludeColumns) > 0 {
+		columns, err := describeQuery(sessionSetup(fileFormat, opts), query)
+		if err != nil {
+			return "", err
+		}
+		selectList, err := excludeSelectList(columns, opts.excludeColumns)
+		if err != nil {
+			return "", err
+		}
+		query = fmt.Sprintf(`SELECT %s FROM (%s)`, selectList, query)
+	}
+	if len(opts.renames) > 0 {
+		columns, err := describeQuery(sessionSetup(fileFormat, opts), query)
+		if err != nil {
+			return "", err
+		}
+		selectList, err := renameSelectList(columns, opts.renames)
+		if err != nil {
+			return "", err
+		}
+		query = fmt.Sprintf(`SELECT %s FROM (%s)`, selectList, query)
+	}
+	if opts.lowercaseColumns {
+		columns, err := describeQuery(sessionSetup(fileFormat, opts), query)
+		if err != nil {
+			return "", err
+		}
+		selectList, err := lowercaseSelectList(columns)
+		if err != nil {
+			return "", err
+		}
+		query = fmt.Sprintf(`SELECT %s FROM (%s)`, selectList, query)
+	}
+
+	// Numbered in scan order, which is the file order for a single file
+	if opts.rownum {
+		query = fmt.Sprintf(`SELECT row_number() OVER () AS rn, * FROM (%s)`, query)
+	}
+	return query, nil
+}
+
+// csvReadParams returns the read_csv parameters for the options, shared by the load and every query
+// that reads the file the same way
+func csvReadParams(opts tableOptions) []string {
+	params := []string{fmt.Sprintf("strict_mode=%v", opts.strict)}
+	if opts.compression != "" {
+		params = append(params, "compression="+quoteLiteral(opts.compression))
+	}
+	if opts.delim != "" {
+		params = append(params, "delim="+quoteLiteral(opts.delim))
+	}
+	if opts.dateFormat != "" {
+		params = append(params, "dateformat="+quoteLiteral(opts.dateFormat))
+	}
+	if opts.timestampFormat != "" {
+		params = append(params, "timestampformat="+quoteLiteral(opts.timestampFormat))
+	}
+	if opts.maxLineSize > 0 {
+		params = append(params, fmt.Sprintf("max_line_size=%d", opts.maxLineSize))
+	}
+	if opts.noHeader {
+		params = append(params, "header=false")
+	}
+	if opts.sampleSize != 0 && len(opts.fastColumns) == 0 {
+		params = append(params, fmt.Sprintf("sample_size=%d", opts.sampleSize))
+	}
+	if len(opts.fastColumns) > 0 {
+		// Without auto-detection DuckDB needs the columns spelled out
+		params = append(params, "auto_detect=false", "header=true", "columns="+varcharColumnsStruct(opts.fastColumns))
+	} else if opts.allVarchar {
+		params = append(params, "all_varchar=true")
+	} else if len(opts.columnTypes) > 0 {
+		params = append(params, "types="+columnTypesStruct(opts.columnTypes))
+	}
+	return params
+}
+
+// whereClause returns the condition filtering the rows while they are read, so DuckDB can push it
+// into the scan, or "" when all rows are kept
+func whereClause(opts tableOptions) string {
+	var conditions []string
+	if len(opts.partitionFilter) > 0 {
+		conditions = append(conditions, partitionWhereClause(opts.partitionFilter))
+	}
+	if opts.where != "" {
+		conditions = append(conditions, "("+opts.where+")")
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// readFunction returns the DuckDB table function call reading the files
+func readFunction(filename FileNameString, fileFormat FileFormat, params []string) string {
+	var args string
+	// Several CSV or JSON files, like the members of a tar archive, are passed as a list too
+	if fileFormat == Parquet || fileFormat == Arrow || strings.Contains(string(filename), "','") {
+		args = fmt.Sprintf("[%s]", filename)
+	} else {
+		args = string(filename)
+	}
+	for _, p := range params {
+		args += ", " + p
+	}
+
+	switch fileFormat {
+	case Delta:
+		return fmt.Sprintf("delta_scan(%s)", args)
+	case Iceberg:
+		return fmt.Sprintf("iceberg_scan(%s)", args)
+	default:
+		return fmt.Sprintf("read_%s(%s)", fileFormat, args)
+	}
+}
+
+// utcSetting makes DuckDB convert TIMESTAMPTZ values from and to UTC instead of the local time zone.
+// TIMESTAMP, DATE and TIME values have no time zone and are not affected.
+const utcSetting = "SET TimeZone = 'UTC'; "
+
+// sessionSetup returns the statements that must run in a duckdb session before the files can be read
+func sessionSetup(fileFormat FileFormat, opts tableOptions) string {
+	var setup string
+	if opts.noAutoload {
+		setup += noAutoloadSettings
+	}
+	if opts.utc {
+		setup += utcSetting
+	}
+	setup += opts.settings
+	if ext := requiredExtension(fileFormat); ext != "" {
+		setup += fmt.Sprintf("LOAD %s; ", ext)
+	}
+	if opts.remote {
+		setup += "LOAD httpfs; "
+		if opts.s3.configured() {
+			setup += opts.s3.secretStatement()
+		}
+	}
+	return setup
+}
+
+// loadSettings returns the SET statements that must run before the files are loaded. They only apply
+// to the duckdb process loading the table, not to the interactive session.
+func loadSettings(opts tableOptions) string {
+	var settings string
+	// Insertion order is preserved by default, but with several threads the files are still
+	// scanned in parallel. The sampled rows depend on that order too, so a seeded sample is
+	// loaded the same way.
+	// The seed only changes the load for --sample, --random-file uses it to pick the file
+	seededSample := opts.sample != "" && opts.sampleSeed != ""
+	if opts.deterministic || seededSample {
+		settings += "SET preserve_insertion_order = true; SET threads = 1; "
+	}
+	// A variable is set rather than selecting setseed(), which would print its result
+	if seededSample {
+		settings += fmt.Sprintf("SET VARIABLE dpi_sample_seed = setseed(%s); ", opts.sampleSeed)
+	}
+	return settings
+}
+
+// createTableStatement returns the SQL that loads the files into the table, including the extension
+// the format needs
+func createTableStatement(create string, tableName string, filename FileNameString, fileFormat FileFormat, opts tableOptions) (string, error) {
+	selectQuery, err := buildSelectQuery(filename, fileFormat, opts)
+	if err != nil {
+		return "", err
+	}
+	return sessionSetup(fileFormat, opts) + fmt.Sprintf(`%s %s AS %s;`, create, quoteIdentifier(tableName), selectQuery), nil
+}
+
+func createTemporaryTable(tableName string, filename FileNameString, duckdbPath string, fileFormat FileFormat, opts tableOptions) error {
+	// A persistent --database may already hold the table from an earlier run
+	create := "CREATE TABLE"
+	if fileExists(duckdbPath) {
+		exists, err := tableExists(duckdbPath, tableName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			if err := confirmOverwrite(duckdbPath, tableName, opts.force); err != nil {
+				return err
+			}
+			create = "CREATE OR REPLACE TABLE"
+		}
+	}
+	query, err := createTableStatement(create, tableName, filename, fileFormat, opts)
+	if err != nil {
+		return err
+	}
+	query = loadSettings(opts) + query
+
 	cmds := []string{
 		"duckdb",
 		duckdbPath,
@@ -122,22 +620,49 @@ func createTemporaryTable(filename FileNameString, tempDir string, fileFormat Fi
 	return nil
 }
 
-func findParquetFiles(pattern string) ([]string, error) {
-	files, err := filepath.Glob(pattern)
-	if err != nil {
-		// Glob only returns ErrBadPattern, which is unlikely with user input
-		// but we'll handle it anyway
-		return nil, fmt.Errorf("invalid file pattern '%s': %w", pattern, err)
+// findParquetFiles returns the files matching the pattern. filepath.Glob knows no braces, so
+// alternatives like {2023,2024} are expanded first and the matches of all patterns are combined.
+func findParquetFiles(patterns ...string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		for _, p := range expandBraces(pattern) {
+			matches, err := filepath.Glob(p)
+			if err != nil {
+				// Glob only returns ErrBadPattern, which is unlikely with user input
+				// but we'll handle it anyway
+				return nil, fmt.Errorf("invalid file pattern '%s': %w", pattern, err)
+			}
+			for _, m := range matches {
+				if !seen[m] {
+					seen[m] = true
+					files = append(files, m)
+				}
+			}
+		}
 	}
 	return files, nil
 }
 
-func executeCommand(args []string) error {
+// newCommand returns the command for the arguments, with its secrets moved into a file by hideSecrets.
+// The returned function removes that file once the command has run.
+func newCommand(args []string) (*exec.Cmd, func(), error) {
 	if len(args) == 0 {
-		return fmt.Errorf("no command provided")
+		return nil, nil, fmt.Errorf("no command provided")
 	}
+	args, cleanup, err := hideSecrets(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	return exec.Command(args[0], args[1:]...), cleanup, nil
+}
 
-	cmd := exec.Command(args[0], args[1:]...)
+func executeCommand(args []string) error {
+	cmd, cleanup, err := newCommand(args)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -145,23 +670,112 @@ func executeCommand(args []string) error {
 	return cmd.Run()
 }
 
+// captureCommand runs the command like executeCommand but returns its standard output
+// instead of printing it. Standard error is still passed through to the user.
+func captureCommand(args []string) ([]byte, error) {
+	cmd, cleanup, err := newCommand(args)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// runQuery executes the SQL against the database non-interactively, printing the result to stdout.
+// outputArgs are extra duckdb CLI flags such as the output mode.
+func runQuery(duckdbPath string, query string, outputArgs []string) error {
+	cmds := append([]string{"duckdb", duckdbPath}, outputArgs...)
+	cmds = append(cmds, "-c", query)
+	if err := executeCommand(cmds); err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	return nil
+}
+
+// runSQLFile runs the statements of the SQL file against the database, stopping at the first error
+func runSQLFile(duckdbPath string, sqlPath string, outputArgs []string) error {
+	cmds := append([]string{"duckdb", duckdbPath, "-bail"}, outputArgs...)
+	cmds = append(cmds, "-f", sqlPath)
+	if err := executeCommand(cmds); err != nil {
+		return fmt.Errorf("failed to run %s: %w", sqlPath, err)
+	}
+	return nil
+}
+
+// queryScalar runs the query against the database and returns the single value it produces
+func queryScalar(duckdbPath string, query string) (string, error) {
+	output, err := captureCommand([]string{"duckdb", duckdbPath, "-csv", "-noheader", "-c", query})
+	if err != nil {
+		return "", fmt.Errorf("failed to execute query: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// wrapExecQuery surrounds the --exec query with the --prepend-sql and --append-sql statements, so they
+// run in the same -c argument: the prepended ones first, the appended ones only when the query succeeds
+func wrapExecQuery(cmd *cobra.Command, query string) string {
+	if prepend := trimStatement(cmd.Flag("prepend-sql").Value.String()); prepend != "" {
+		query = prepend + "; " + query
+	}
+	if appended := trimStatement(cmd.Flag("append-sql").Value.String()); appended != "" {
+		query = trimStatement(query) + "; " + appended + ";"
+	}
+	return query
+}
+
+// trimStatement strips whitespace and trailing semicolons so a single statement can be used as a subquery
+func trimStatement(query string) string {
+	return strings.TrimRight(strings.TrimSpace(query), "; \t\n")
+}
+
+// duckDBVersionPattern matches the output of duckdb --version, e.g. "v1.2.1 8e52ec4395"
+var duckDBVersionPattern = regexp.MustCompile(`^v\d+\.\d+`)
+
+// ensureDuckDBBinary checks that duckdb is in the PATH and actually runs as DuckDB, so a broken symlink
+// or an unrelated program of the same name fails here instead of with a cryptic error later
 func ensureDuckDBBinary() error {
-	_, err := exec.LookPath("duckdb")
+	path, err := exec.LookPath("duckdb")
 	if err != nil {
+		// LookPath skips entries it cannot stat, which hides dangling symlinks
+		for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+			candidate := filepath.Join(dir, "duckdb")
+			if info, err := os.Lstat(candidate); err == nil && info.Mode()&os.ModeSymlink != 0 && !fileExists(candidate) {
+				return fmt.Errorf("found 'duckdb' at %s but it is a broken symlink. Please reinstall DuckDB: https://duckdb.org/docs/installation/", candidate)
+			}
+		}
 		return fmt.Errorf("DuckDB binary not found in system PATH. Please install DuckDB: https://duckdb.org/docs/installation/")
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("found 'duckdb' at %s but it failed to run (%v). Please reinstall DuckDB: https://duckdb.org/docs/installation/", path, err)
+	}
+	if !duckDBVersionPattern.Match(bytes.TrimSpace(output)) {
+		return fmt.Errorf("found 'duckdb' at %s but it doesn't appear to be DuckDB ('duckdb --version' printed %q)", path, strings.TrimSpace(string(output)))
+	}
 	return nil
 }
 
+// signalReceived is set by the signal handler, so code handling a failed DuckDB run can tell
+// whether it was interrupted (using atomic for thread-safety)
+var signalReceived atomic.Bool
+
 // setupSignalHandler sets up signal handling for graceful cleanup.
 // It returns a cleanup function that should be deferred.
 func setupSignalHandler() func() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Track whether we received a signal (using atomic for thread-safety)
-	var signalReceived atomic.Bool
-
 	// Start goroutine to handle signals
 	go func() {
 		sig := <-sigChan
@@ -185,71 +799,693 @@ func runCommand(cmd *cobra.Command, args []string) {
 	cleanupSignalHandler := setupSignalHandler()
 	defer cleanupSignalHandler()
 
-	fmt.Fprintln(os.Stdout, "============== Initial dpi setup ==============")
+	opts, err := readOptions(cmd)
+	if err != nil {
+		exitWithError("%v", err)
+	}
+	opts.force = cmd.Flag("force").Value.String() == "true"
+	database := cmd.Flag("database").Value.String()
+	if opts.force && database == "" {
+		if !fromEnvironment(cmd, "force") {
+			exitWithError("--force requires --database")
+		}
+		opts.force = false
+	}
+
+	schemaMode := cmd.Flag("schema").Value.String() == "true"
+	schemaFormat, err := parseSchemaFormat(cmd.Flag("schema-format").Value.String())
+	if err != nil {
+		exitWithError("%v", err)
+	}
+	if cmd.Flag("schema-format").Changed && !schemaMode {
+		exitWithError("--schema-format requires --schema")
+	}
+
+	execQuery := cmd.Flag("exec").Value.String()
+	transpose := cmd.Flag("transpose").Value.String() == "true"
+	if transpose && execQuery == "" && !flagGiven(cmd, "run") && !flagGiven(cmd, "run-file") {
+		if !fromEnvironment(cmd, "transpose") {
+			exitWithError("--transpose requires --exec, --run or --run-file")
+		}
+		transpose = false
+	}
+	outputFormat, err := parseOutputFormat(cmd.Flag("output-format").Value.String())
+	if err != nil {
+		exitWithError("%v", err)
+	}
+
+	runFile := cmd.Flag("run-file").Value.String()
+	if runFile != "" && !fileExists(runFile) {
+		exitWithError("SQL file does not exist: %s", runFile)
+	}
+
+	perFile := cmd.Flag("per-file").Value.String() == "true"
+	keepGoing := cmd.Flag("keep-going").Value.String() == "true"
+	if perFile && execQuery == "" {
+		exitWithError("--per-file requires --exec")
+	}
+	if keepGoing && !perFile {
+		if !fromEnvironment(cmd, "keep-going") {
+			exitWithError("--keep-going requires --per-file")
+		}
+		keepGoing = false
+	}
+	parallel, err := cmd.Flags().GetInt("parallel")
+	if err != nil || parallel < 1 {
+		exitWithError("--parallel must be at least 1")
+	}
+	if cmd.Flags().Changed("parallel") && !perFile {
+		exitWithError("--parallel requires --per-file")
+	}
+	concatPath := cmd.Flag("concat-output").Value.String()
+	if concatPath != "" && !perFile {
+		if !fromEnvironment(cmd, "concat-output") {
+			exitWithError("--concat-output requires --per-file")
+		}
+		concatPath = ""
+	}
+	if concatPath != "" && outputFormat != OutputCSV && outputFormat != OutputNDJSON {
+		exitWithError("--concat-output requires --output-format csv or ndjson, whose results can be appended")
+	}
 
-	filePath := args[0]
-	strict := cmd.Flag("strict").Value.String() == "true"
-	allVarchar := cmd.Flag("all-varchar").Value.String() == "true"
+	distinct := cmd.Flag("distinct").Value.String() == "true"
+	dialect, err := parseDialect(cmd.Flag("dialect").Value.String())
+	if err != nil {
+		exitWithError("%v", err)
+	}
+	if dialect == DialectPRQL {
+		if execQuery == "" {
+			exitWithError("--dialect prql requires --exec")
+		}
+		if distinct {
+			exitWithError("--distinct cannot wrap a PRQL query, use PRQL's group or distinct instead")
+		}
+		if concatPath != "" {
+			exitWithError("--concat-output cannot wrap a PRQL query")
+		}
+		if err := ensureExtension("prql", !opts.noAutoload); err != nil {
+			exitWithError("%v", err)
+		}
+		// Once loaded, the extension parses the statements DuckDB's SQL parser does not accept as PRQL
+		execQuery = "LOAD prql; " + execQuery
+	}
+	if distinct && execQuery != "" {
+		execQuery = fmt.Sprintf("SELECT DISTINCT * FROM (%s);", trimStatement(execQuery))
+	}
+	// The rows of every file are marked with the file name, which runPerFile sets in the variable
+	if concatPath != "" {
+		execQuery = fmt.Sprintf("SELECT getvariable('%s') AS source, * FROM (%s);", sourceVariable, trimStatement(execQuery))
+	}
+	if (cmd.Flags().Changed("prepend-sql") || cmd.Flags().Changed("append-sql")) && execQuery == "" {
+		exitWithError("--prepend-sql and --append-sql require --exec")
+	}
+	// The statements wrap the complete query, so they stay outside of --distinct and run before LOAD prql
+	if execQuery != "" {
+		execQuery = wrapExecQuery(cmd, execQuery)
+	}
 
-	// Determine file format
-	fileFormat := determineFileFormat(filePath)
-	if fileFormat == "" {
-		exitWithError("Unsupported file format for file: %s", filePath)
+	regex := cmd.Flag("regex").Value.String() == "true"
+	if regex && !flagGiven(cmd, "find") {
+		if !fromEnvironment(cmd, "regex") {
+			exitWithError("--regex requires --find")
+		}
+		regex = false
+	}
+	approx := cmd.Flag("approx").Value.String() == "true"
+	if approx && cmd.Flag("count-distinct").Value.String() == "" {
+		if !fromEnvironment(cmd, "approx") {
+			exitWithError("--approx requires --count-distinct")
+		}
+		approx = false
+	}
+
+	var groupColumns []string
+	for _, name := range strings.Split(cmd.Flag("group-by").Value.String(), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			groupColumns = append(groupColumns, name)
+		}
+	}
+	if cmd.Flags().Changed("group-by") && len(groupColumns) == 0 {
+		exitWithError("--group-by requires at least one column")
+	}
+	agg := strings.TrimSpace(cmd.Flag("agg").Value.String())
+	if cmd.Flags().Changed("agg") && (len(groupColumns) == 0 || agg == "") {
+		exitWithError("--agg requires --group-by and an aggregate expression")
+	}
+	groupLimit, err := cmd.Flags().GetInt("limit")
+	if err != nil || groupLimit < 0 {
+		exitWithError("--limit must be a positive number")
+	}
+	if cmd.Flags().Changed("limit") && len(groupColumns) == 0 {
+		exitWithError("--limit requires --group-by")
+	}
+
+	var topColumn string
+	var topLimit int
+	if spec := cmd.Flag("top").Value.String(); spec != "" {
+		if topColumn, topLimit, err = parseColumnSpec("top", spec, topReportLimit); err != nil {
+			exitWithError("%v", err)
+		}
+	}
+	var histogramColumn string
+	var buckets int
+	if spec := cmd.Flag("histogram").Value.String(); spec != "" {
+		if histogramColumn, buckets, err = parseColumnSpec("histogram", spec, histogramBuckets); err != nil {
+			exitWithError("%v", err)
+		}
+	}
+
+	checkRows := flagGiven(cmd, "min-rows") || flagGiven(cmd, "max-rows-assert")
+	bounds, err := readRowCountBounds(cmd)
+	if err != nil {
+		exitWithError("%v", err)
+	}
+
+	// Only the interactive session keeps the setup messages on stdout
+	if isBatchMode(cmd) || distinct {
+		statusOut = os.Stderr
+	}
+	summaryOnExit := cmd.Flag("summary-on-exit").Value.String() == "true"
+	if summaryOnExit && (isBatchMode(cmd) || distinct) {
+		if !fromEnvironment(cmd, "summary-on-exit") {
+			exitWithError("--summary-on-exit only applies to the interactive session")
+		}
+		summaryOnExit = false
+	}
+
+	startQuery := cmd.Flag("start-query").Value.String()
+	if startQuery != "" && (isBatchMode(cmd) || distinct) {
+		if !fromEnvironment(cmd, "start-query") {
+			exitWithError("--start-query only applies to the interactive session")
+		}
+		startQuery = ""
 	}
-	fmt.Fprintf(os.Stdout, "Detected file format: %s\n", fileFormat)
+
+	printSQL := cmd.Flag("print-sql").Value.String() == "true"
+	if printSQL {
+		statusOut = io.Discard
+	}
+
+	// Resolve the snippet before doing any work so a typo fails fast
+	var snippetPath string
+	if name := cmd.Flag("run").Value.String(); name != "" {
+		if snippetPath, err = findSnippet(name); err != nil {
+			exitWithError("%v", err)
+		}
+	}
+
+	fmt.Fprintln(statusOut, "============== Initial dpi setup ==============")
 
 	// Create temporary directory
-	tempDir, err := createTempDirectory()
+	tempDir, err := createTempDirectory(cmd.Flag("temp-dir").Value.String())
 	if err != nil {
 		exitWithError("Failed to create temporary directory: %v", err)
 	}
 	defer os.RemoveAll(tempDir) // Clean up the temporary directory after use
-	fmt.Fprintf(os.Stdout, "Using temporary directory: %s\n", tempDir)
+	fmt.Fprintf(statusOut, "Using temporary directory: %s\n", tempDir)
 
-	// Process files based on format
-	filename, err := processInputFiles(filePath, fileFormat)
-	if err != nil {
+	// A DuckDB database is opened as it is, there is nothing to load
+	if len(args) == 1 && isDuckDBDatabase(args[0]) {
+		// Without a file format, the setup is only --no-autoload-known-extensions, --utc and the --set statements
+		if err := runDatabaseSession(cmd, args[0], tempDir, sessionSetup("", opts), execQuery); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+
+	// Determine the format and files of every input
+	inputs := make([]inputTable, 0, len(args))
+	if cmd.Flag("union").Value.String() == "true" {
+		input, err := prepareUnionInput(cmd, args, tempDir, opts)
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		inputs = append(inputs, input)
+	} else {
+		for _, filePath := range args {
+			input, err := prepareInput(cmd, filePath, tempDir, opts)
+			if err != nil {
+				exitWithError("%v", err)
+			}
+			inputs = append(inputs, input)
+		}
+	}
+
+	// Several inputs are loaded into one table each, named after their files
+	if len(inputs) > 1 && !perFile {
+		for _, name := range batchModeFlags {
+			if flagGiven(cmd, name) && !multiInputFlags[name] {
+				exitWithError("--%s requires a single input", name)
+			}
+		}
+		if distinct {
+			exitWithError("--distinct requires a single input")
+		}
+		assignTableNames(inputs)
+	}
+
+	// Row groups are read from the file metadata, so no table is needed
+	if cmd.Flag("row-groups").Value.String() == "true" {
+		input := inputs[0]
+		if input.fileFormat != Parquet {
+			exitWithError("--row-groups is only supported for Parquet files")
+		}
+		if err := printRowGroups(sessionSetup(input.fileFormat, input.opts), input.files); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+
+	if cmd.Flag("parquet-schema").Value.String() == "true" {
+		input := inputs[0]
+		if input.fileFormat != Parquet {
+			exitWithError("--parquet-schema is only supported for Parquet files")
+		}
+		if err := printParquetSchema(sessionSetup(input.fileFormat, input.opts), input.files, outputFormatArgs(outputFormat)); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+
+	// The plan is analyzed over the files, so no table is loaded
+	if cmd.Flag("explain-pruning").Value.String() == "true" {
+		if err := printPruning(inputs[0]); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+
+	// Parquet statistics often answer this without reading the data, so no table is loaded
+	if name := cmd.Flag("min-max").Value.String(); name != "" {
+		if err := printMinMax(inputs[0], name); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+
+	// Print the statements instead of running them so they can be pasted into another session
+	if printSQL {
+		for _, input := range inputs {
+			statement, err := createTableStatement("CREATE TABLE", input.name, input.filename(), input.fileFormat, input.opts)
+			if err != nil {
+				exitWithError("%v", err)
+			}
+			for _, f := range input.files {
+				if strings.HasPrefix(f, tempDir) {
+					fmt.Fprintf(os.Stderr, "Warning: %s is read from a temporary copy that is removed when dpi exits\n", input.path)
+					break
+				}
+			}
+			fmt.Fprintln(os.Stdout, maskSecrets(loadSettings(input.opts)+statement, input.opts))
+		}
+		return
+	}
+
+	// Columns are checked against the schema of the input files, no table is needed
+	if list := cmd.Flag("require-columns").Value.String(); list != "" {
+		var required []string
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				required = append(required, name)
+			}
+		}
+		failed := false
+		for _, input := range inputs {
+			if err := checkRequiredColumns(input, required); err != nil {
+				reportError(err.Error(), 1)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Print the schema straight from the input files, no table is needed
+	if schemaMode {
+		input := inputs[0]
+		query, err := buildSelectQuery(input.filename(), input.fileFormat, input.opts)
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		if err := printSchema(sessionSetup(input.fileFormat, input.opts), query, schemaFormat); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+
+	// Nested JSON reads as STRUCT and list columns, whose members are easier to follow as a tree
+	if cmd.Flag("structure").Value.String() == "true" {
+		input := inputs[0]
+		query, err := buildSelectQuery(input.filename(), input.fileFormat, input.opts)
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		columns, err := describeQuery(sessionSetup(input.fileFormat, input.opts), query)
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		tree, err := structureTree(columns)
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		fmt.Fprint(os.Stdout, tree)
+		return
+	}
+
+	// The types are those the table would be created with, read from the input files
+	if cmd.Flag("ddl").Value.String() == "true" {
+		for _, input := range inputs {
+			query, err := buildSelectQuery(input.filename(), input.fileFormat, input.opts)
+			if err != nil {
+				exitWithError("%v", err)
+			}
+			columns, err := describeQuery(sessionSetup(input.fileFormat, input.opts), query)
+			if err != nil {
+				exitWithError("%v", err)
+			}
+			fmt.Fprintln(os.Stdout, createTableDDL(input.name, columns))
+		}
+		return
+	}
+
+	// The table is kept in --database after dpi exits, otherwise it lives in the temporary directory
+	duckdbPath := filepath.Join(tempDir, "tmp.duckdb")
+	if database != "" {
+		duckdbPath = database
+	}
+
+	// Session settings are passed to every duckdb invocation below through an init script
+	var sessionCommands []string
+	if opts.noAutoload {
+		sessionCommands = append(sessionCommands, noAutoloadSettings)
+	}
+	if opts.utc {
+		sessionCommands = append(sessionCommands, utcSetting)
+	}
+	if opts.settings != "" {
+		sessionCommands = append(sessionCommands, opts.settings)
+	}
+	width, err := cmd.Flags().GetInt("width")
+	if err != nil || width < 0 {
+		exitWithError("--width must be a positive number")
+	}
+	if w := outputWidth(width); w > 0 {
+		sessionCommands = append(sessionCommands, fmt.Sprintf(".maxwidth %d", w))
+	}
+	// The query is echoed so its result can be told apart in the session
+	if startQuery != "" {
+		sessionCommands = append(sessionCommands, ".echo on", trimStatement(startQuery)+";", ".echo off")
+	}
+
+	var outputArgs []string
+	var initFile string
+	if len(sessionCommands) > 0 {
+		if initFile, err = writeInitFile(tempDir, sessionCommands); err != nil {
+			exitWithError("%v", err)
+		}
+		outputArgs = append(outputArgs, "-init", initFile)
+	}
+
+	// DuckDB's line mode prints one "column = value" line per column, with a blank line between rows
+	if transpose {
+		outputArgs = append(outputArgs, "-line")
+	}
+	outputArgs = append(outputArgs, outputFormatArgs(outputFormat)...)
+
+	// New rows are read from the file as it grows, nothing is loaded up front
+	if cmd.Flag("follow").Value.String() == "true" {
+		if err := followCSV(inputs[0], tempDir, outputArgs); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+
+	// Load every file on its own and run the query against it. One file is loaded at a time per
+	// duckdb process, so the largest file has to fit.
+	if perFile {
+		if err := checkTableSpace(cmd, tempDir, []inputTable{largestInput(splitPerFile(inputs))}); err != nil {
+			exitWithError("%v", err)
+		}
+		var concat *concatOutput
+		if concatPath != "" {
+			out := os.Stdout
+			if concatPath != "-" {
+				if out, err = os.Create(concatPath); err != nil {
+					exitWithError("failed to create %s: %v", concatPath, err)
+				}
+				defer out.Close()
+			}
+			concat = &concatOutput{w: out, csv: outputFormat == OutputCSV}
+		}
+		if err := runPerFile(inputs, tempDir, execQuery, outputArgs, keepGoing, parallel, concat); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+
+	// Check that the tables fit where they are written, the directory of --database if given
+	spaceDir := tempDir
+	if database != "" {
+		spaceDir = filepath.Dir(database)
+	}
+	if err := checkTableSpace(cmd, spaceDir, inputs); err != nil {
 		exitWithError("%v", err)
 	}
 
-	// Create temporary table
-	if err := createTemporaryTable(filename, tempDir, fileFormat, strict, allVarchar); err != nil {
-		exitWithError("Creating temporary table failed: %v", err)
+	// An interrupted CREATE TABLE is rolled back by DuckDB, which leaves an existing --database as it
+	// was. A database file created by this run would only hold some of the tables, so it is removed.
+	createdDatabase := database != "" && !fileExists(database)
+
+	// Create temporary tables
+	for _, input := range inputs {
+		if err := createTemporaryTable(input.name, input.filename(), duckdbPath, input.fileFormat, input.opts); err != nil {
+			if createdDatabase && signalReceived.Load() {
+				removeDatabase(database)
+			}
+			exitWithError("Creating temporary table failed: %v", err)
+		}
+		if len(inputs) > 1 {
+			fmt.Fprintf(statusOut, "Loaded %s into table %s\n", input.path, input.name)
+		}
+	}
+	fmt.Fprintln(statusOut, "Temporary table created successfully")
+	if cmd.Flag("mem-report").Value.String() == "true" {
+		if err := printMemoryReport(duckdbPath); err != nil {
+			exitWithError("%v", err)
+		}
+	}
+
+	if checkRows {
+		failed := false
+		for _, input := range inputs {
+			if err := checkRowCount(duckdbPath, input.name, bounds); err != nil {
+				reportError(err.Error(), 1)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Run the query or snippet instead of the interactive session
+	if execQuery != "" {
+		if err := runQuery(duckdbPath, execQuery, outputArgs); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if distinct {
+		if err := printDistinctCounts(duckdbPath, outputArgs); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if cmd.Flag("nulls").Value.String() == "true" {
+		if err := printNullReport(duckdbPath, outputArgs); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if name := cmd.Flag("unique").Value.String(); name != "" {
+		if err := checkUnique(duckdbPath, name, outputArgs); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if topColumn != "" {
+		if err := printTopValues(duckdbPath, topColumn, topLimit, outputArgs); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if len(groupColumns) > 0 {
+		if err := printGroups(duckdbPath, groupColumns, agg, groupLimit, outputArgs); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if name := cmd.Flag("count-distinct").Value.String(); name != "" {
+		if err := printDistinctCount(duckdbPath, name, approx); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if flagGiven(cmd, "find") {
+		if err := printValueSearch(duckdbPath, cmd.Flag("find").Value.String(), regex); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if spec := cmd.Flag("corr").Value.String(); spec != "" {
+		if err := printCorrelation(duckdbPath, spec); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if histogramColumn != "" {
+		if err := printHistogram(duckdbPath, histogramColumn, buckets); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if cmd.Flag("type-report").Value.String() == "true" {
+		if inputs[0].fileFormat != CSV {
+			exitWithError("--type-report is only supported for CSV files")
+		}
+		if err := printTypeReport(duckdbPath); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if cmd.Flag("checksum").Value.String() == "true" {
+		if err := printChecksum(duckdbPath); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+	if runFile != "" {
+		if err := runSQLFile(duckdbPath, runFile, outputArgs); err != nil {
+			exitWithCommandError(err)
+		}
+		return
+	}
+	if snippetPath != "" {
+		fmt.Fprintln(statusOut, "============== Running snippet ==============")
+		if err := runSnippet(duckdbPath, snippetPath, outputArgs); err != nil {
+			exitWithError("%v", err)
+		}
+		return
+	}
+
+	// The banner is printed by the init script, so it goes before the start-up query
+	var interactiveCommands []string
+	if cmd.Flag("no-banner").Value.String() != "true" {
+		if interactiveCommands, err = tableBanner(duckdbPath); err != nil {
+			exitWithError("%v", err)
+		}
+	}
+	interactiveCommands = append(interactiveCommands, promptCommand(cmd, args[0]))
+	hadInitFile := initFile != ""
+	if initFile, err = writeInitFile(tempDir, append(interactiveCommands, sessionCommands...)); err != nil {
+		exitWithError("%v", err)
+	}
+	if !hadInitFile {
+		outputArgs = append(outputArgs, "-init", initFile)
 	}
-	fmt.Fprintln(os.Stdout, "Temporary table created successfully")
 
 	// Start DuckDB CLI
-	fmt.Fprintln(os.Stdout, "============== Starting DuckDB CLI ==============")
-	duckdbPath := filepath.Join(tempDir, "tmp.duckdb")
-	cmds := []string{"duckdb", duckdbPath}
+	fmt.Fprintln(statusOut, "============== Starting DuckDB CLI ==============")
+	cmds := append([]string{"duckdb", duckdbPath}, outputArgs...)
 
 	if err := executeCommand(cmds); err != nil {
 		exitWithError("Failed to execute DuckDB: %v", err)
 	}
+
+	if summaryOnExit {
+		for _, input := range inputs {
+			printRowSummary(duckdbPath, input.name)
+		}
+	}
 }
 
-func processInputFiles(filePath string, fileFormat FileFormat) (FileNameString, error) {
-	if fileFormat == Parquet {
-		// For Parquet files, handle multiple files using glob patterns
-		files, err := findParquetFiles(filePath)
-		if err != nil {
-			return "", err
+// matchParquetFiles returns the non-empty files matching any of the patterns, each file once. It is an
+// error if no files match at all, not if a single pattern matches nothing.
+func matchParquetFiles(patterns []string) ([]string, error) {
+	files, err := findParquetFiles(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found matching pattern: %s", strings.Join(patterns, ", "))
+	}
+
+	// Truncated downloads leave zero-byte files that DuckDB fails on with a confusing error
+	nonEmpty := files[:0]
+	for _, f := range files {
+		if isEmptyFile(f) {
+			fmt.Fprintf(os.Stderr, "Warning: skipping empty file: %s\n", f)
+			continue
 		}
-		if len(files) == 0 {
-			return "", fmt.Errorf("no Parquet files found matching pattern: %s", filePath)
+		nonEmpty = append(nonEmpty, f)
+	}
+	if len(nonEmpty) == 0 {
+		return nil, fmt.Errorf("all files matching %s are empty", strings.Join(patterns, ", "))
+	}
+	// A pattern matching far more or fewer files than expected is easier to spot before the load
+	if slices.ContainsFunc(patterns, isGlobPattern) {
+		var size int64
+		for _, f := range nonEmpty {
+			if info, err := os.Stat(f); err == nil {
+				size += info.Size()
+			}
 		}
+		fmt.Fprintf(os.Stderr, "Matched %d file(s), %s in total\n", len(nonEmpty), formatBytes(size))
+	}
+	return nonEmpty, nil
+}
 
-		// Create a single FileNameString by concatenating files separated by commas
-		var filenames []string
-		for _, f := range files {
-			filenames = append(filenames, "'"+f+"'")
-		}
-		return FileNameString(strings.Join(filenames, ",")), nil
+// isGlobPattern reports whether the path contains wildcards or braces that expand to several files
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[{")
+}
+
+func processInputFiles(filePath string, fileFormat FileFormat, tempDir string, noGlob bool) ([]string, error) {
+	if fileFormat == Delta || fileFormat == Iceberg || isRemotePath(filePath) {
+		// Table formats are read as a whole directory, and DuckDB resolves remote paths itself
+		return []string{filePath}, nil
+	}
+	if (fileFormat == Parquet || fileFormat == Arrow) && !noGlob {
+		// For Parquet and Arrow files, handle multiple files using glob patterns
+		return matchParquetFiles([]string{filePath})
 	} else {
-		// For other file formats, check if file exists
+		// For other file formats and literal names, check if file exists
 		if !fileExists(filePath) {
-			return "", fmt.Errorf("file does not exist: %s", filePath)
+			return nil, fmt.Errorf("file does not exist: %s", filePath)
+		}
+		if isEmptyFile(filePath) {
+			return nil, fmt.Errorf("file is empty: %s", filePath)
+		}
+		if needsDecompression(filePath) {
+			decompressed, err := decompressFile(filePath, tempDir)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(statusOut, "Decompressed %s\n", filePath)
+			return []string{decompressed}, nil
 		}
-		return FileNameString("'" + filePath + "'"), nil
+		return []string{filePath}, nil
+	}
+}
+
+// toFileNameString creates a single FileNameString by quoting the files and separating them by commas
+func toFileNameString(files []string) FileNameString {
+	var filenames []string
+	for _, f := range files {
+		filenames = append(filenames, "'"+f+"'")
 	}
+	return FileNameString(strings.Join(filenames, ","))
 }
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFile creates the file with the given contents, and its directories, below dir
+func writeTestFile(t *testing.T, dir string, name string, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadFunction(t *testing.T) {
+	tests := []struct {
+		files  []string
+		format FileFormat
+		params []string
+		want   string
+	}{
+		{[]string{"a.parquet"}, Parquet, nil, "read_parquet(['a.parquet'])"},
+		{[]string{"a.parquet", "b.parquet"}, Parquet, []string{"union_by_name=true"}, "read_parquet(['a.parquet','b.parquet'], union_by_name=true)"},
+		{[]string{"a.csv"}, CSV, []string{"strict_mode=false"}, "read_csv('a.csv', strict_mode=false)"},
+		{[]string{"a.csv", "b.csv"}, CSV, nil, "read_csv(['a.csv','b.csv'])"},
+		{[]string{"a.arrow"}, Arrow, nil, "read_arrow(['a.arrow'])"},
+		{[]string{"lake/delta"}, Delta, []string{"version=3"}, "delta_scan('lake/delta', version=3)"},
+		{[]string{"lake/iceberg"}, Iceberg, nil, "iceberg_scan('lake/iceberg')"},
+		{[]string{"lake/iceberg"}, Iceberg, []string{"snapshot_from_id=42"}, "iceberg_scan('lake/iceberg', snapshot_from_id=42)"},
+	}
+	for _, tt := range tests {
+		if got := readFunction(toFileNameString(tt.files), tt.format, tt.params); got != tt.want {
+			t.Errorf("readFunction(%v, %s) = %s, want %s", tt.files, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestBuildSelectQueryTables(t *testing.T) {
+	tests := []struct {
+		name   string
+		format FileFormat
+		opts   tableOptions
+		want   string
+	}{
+		{"iceberg", Iceberg, tableOptions{}, "SELECT * FROM iceberg_scan('t')"},
+		{"iceberg snapshot", Iceberg, tableOptions{snapshot: "4183020680887155442"},
+			"SELECT * FROM iceberg_scan('t', snapshot_from_id=4183020680887155442)"},
+		{"iceberg timestamp", Iceberg, tableOptions{snapshotTimestamp: "2024-06-01 12:00:00"},
+			"SELECT * FROM iceberg_scan('t', snapshot_from_timestamp=TIMESTAMP '2024-06-01 12:00:00')"},
+		{"iceberg all varchar", Iceberg, tableOptions{allVarchar: true}, "SELECT COLUMNS(*):VARCHAR FROM iceberg_scan('t')"},
+		{"delta version", Delta, tableOptions{versionAsOf: "7"}, "SELECT * FROM delta_scan('t', version=7)"},
+	}
+	for _, tt := range tests {
+		got, err := buildSelectQuery(toFileNameString([]string{"t"}), tt.format, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: query = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetermineFileFormatTables(t *testing.T) {
+	dir := t.TempDir()
+	delta := filepath.Join(dir, "delta")
+	writeTestFile(t, delta, "_delta_log/00000000000000000000.json", "{}")
+	iceberg := filepath.Join(dir, "iceberg")
+	writeTestFile(t, iceberg, "metadata/v1.metadata.json", "{}")
+	noMetadata := filepath.Join(dir, "plain")
+	writeTestFile(t, noMetadata, "metadata/readme.txt", "")
+
+	for path, want := range map[string]FileFormat{delta: Delta, iceberg: Iceberg, noMetadata: ""} {
+		if got := determineFileFormat(path); got != want {
+			t.Errorf("determineFileFormat(%s) = %q, want %q", path, got, want)
+		}
+	}
+	if !isIcebergTable(iceberg) || isIcebergTable(delta) || isIcebergTable(noMetadata) {
+		t.Error("isIcebergTable does not only match directories with a *.metadata.json file")
+	}
+}
+
+func TestDetermineFileFormat(t *testing.T) {
+	tests := map[string]FileFormat{
+		"data.parquet":   Parquet,
+		"DATA.PARQUET":   Parquet,
+		"data.csv":       CSV,
+		"dump.txt":       CSV,
+		"dump.TXT":       CSV,
+		"data.json":      JSON,
+		"data.ndjson":    JSON,
+		"data.jsonl":     JSON,
+		"data.arrow":     Arrow,
+		"data.feather":   Arrow,
+		"data.ipc":       Arrow,
+		"data.xlsx":      "",
+		"README":         "",
+		"dir/notes.md":   "",
+		"*.parquet":      Parquet,
+		"logs/2024*.txt": CSV,
+	}
+	for path, want := range tests {
+		if got := determineFileFormat(path); got != want {
+			t.Errorf("determineFileFormat(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestParseFileFormat(t *testing.T) {
+	for in, want := range map[string]FileFormat{"parquet": Parquet, "CSV": CSV, "json": JSON, "arrow": Arrow, "delta": Delta, "Iceberg": Iceberg} {
+		if got, err := parseFileFormat(in); err != nil || got != want {
+			t.Errorf("parseFileFormat(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "txt", "xlsx"} {
+		if _, err := parseFileFormat(in); err == nil {
+			t.Errorf("parseFileFormat(%q) succeeded", in)
+		}
+	}
+}
+
+func TestBuildSelectQueryCSVFormats(t *testing.T) {
+	tests := []struct {
+		opts tableOptions
+		want string
+	}{
+		{tableOptions{}, "SELECT * FROM read_csv('a.csv', strict_mode=false)"},
+		{tableOptions{dateFormat: "%d.%m.%Y"}, "SELECT * FROM read_csv('a.csv', strict_mode=false, dateformat='%d.%m.%Y')"},
+		{tableOptions{timestampFormat: "%d.%m.%Y %H:%M"}, "SELECT * FROM read_csv('a.csv', strict_mode=false, timestampformat='%d.%m.%Y %H:%M')"},
+		{tableOptions{dateFormat: "%Y%m%d", timestampFormat: "%Y%m%d %H'%M"},
+			"SELECT * FROM read_csv('a.csv', strict_mode=false, dateformat='%Y%m%d', timestampformat='%Y%m%d %H''%M')"},
+	}
+	for _, tt := range tests {
+		got, err := buildSelectQuery(toFileNameString([]string{"a.csv"}), CSV, tt.opts)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.opts, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("query = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestIsEmptyFile(t *testing.T) {
+	dir := t.TempDir()
+	empty := writeTestFile(t, dir, "empty.csv", "")
+	full := writeTestFile(t, dir, "full.csv", "a\n1\n")
+	for path, want := range map[string]bool{empty: true, full: false, dir: false, filepath.Join(dir, "missing.csv"): false} {
+		if got := isEmptyFile(path); got != want {
+			t.Errorf("isEmptyFile(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestProcessInputFilesEmpty(t *testing.T) {
+	dir := t.TempDir()
+	empty := writeTestFile(t, dir, "empty.csv", "")
+	writeTestFile(t, dir, "parts/a.parquet", "PAR1")
+	writeTestFile(t, dir, "parts/b.parquet", "")
+	writeTestFile(t, dir, "blank/c.parquet", "")
+
+	if _, err := processInputFiles(empty, CSV, dir, false); err == nil || err.Error() != "file is empty: "+empty {
+		t.Errorf("empty CSV: error = %v, want a file is empty error", err)
+	}
+
+	var files []string
+	var err error
+	_, stderr := captureOutput(t, func() {
+		files, err = processInputFiles(filepath.Join(dir, "parts", "*.parquet"), Parquet, dir, false)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "a.parquet" {
+		t.Errorf("files = %v, want only the non-empty one", files)
+	}
+	if want := "Warning: skipping empty file: " + filepath.Join(dir, "parts", "b.parquet"); !strings.Contains(stderr, want) {
+		t.Errorf("stderr %q is missing %q", stderr, want)
+	}
+
+	captureOutput(t, func() {
+		_, err = processInputFiles(filepath.Join(dir, "blank", "*.parquet"), Parquet, dir, false)
+	})
+	if err == nil || !strings.Contains(err.Error(), "are empty") {
+		t.Errorf("only empty files: error = %v, want an all files are empty error", err)
+	}
+}
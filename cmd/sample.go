@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// parseSample parses the --sample value, a number of rows or a percentage like 10%, into the
+// DuckDB sample clause
+func parseSample(spec string) (string, error) {
+	if percent, ok := strings.CutSuffix(spec, "%"); ok {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p <= 0 || p > 100 {
+			return "", fmt.Errorf("invalid --sample '%s': expected a percentage between 0 and 100", spec)
+		}
+		// Bernoulli samples single rows, the default system sampling whole vectors of rows
+		return fmt.Sprintf("%s%% (bernoulli)", percent), nil
+	}
+	rows, err := strconv.ParseInt(spec, 10, 64)
+	if err != nil || rows <= 0 {
+		return "", fmt.Errorf("invalid --sample '%s': expected a number of rows or a percentage like 10%%", spec)
+	}
+	return fmt.Sprintf("%d ROWS", rows), nil
+}
+
+// parseSampleSeed validates the --sample-seed value, which DuckDB's setseed takes between -1 and 1
+func parseSampleSeed(seed string) (string, error) {
+	s, err := strconv.ParseFloat(seed, 64)
+	if err != nil || s < -1 || s > 1 {
+		return "", fmt.Errorf("invalid --sample-seed '%s': expected a number between -1 and 1", seed)
+	}
+	return strconv.FormatFloat(s, 'f', -1, 64), nil
+}
+
+// pickRandomFile returns one of the files for --random-file. With a --sample-seed the same file is
+// picked on every run, as long as the pattern matches the same files.
+func pickRandomFile(files []string, seed string) string {
+	if seed == "" {
+		return files[rand.IntN(len(files))]
+	}
+	s, _ := strconv.ParseFloat(seed, 64)
+	r := rand.New(rand.NewPCG(math.Float64bits(s), 0))
+	return files[r.IntN(len(files))]
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// column is a single column as reported by DuckDB's DESCRIBE
+type column struct {
+	Name     string
+	Type     string
+	Nullable bool
+}
+
+// describeQuery returns the columns produced by the given SELECT statement without materializing it.
+// setup holds the statements that must run first, see sessionSetup.
+func describeQuery(setup string, query string) ([]column, error) {
+	cmds := []string{
+		"duckdb",
+		"-csv",
+		"-c",
+		setup + "DESCRIBE " + query + ";",
+	}
+
+	output, err := captureCommand(cmds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	return parseDescribeOutput(output)
+}
+
+// describeTable returns the columns of the table in the database
+func describeTable(duckdbPath string) ([]column, error) {
+	cmds := []string{
+		"duckdb",
+		duckdbPath,
+		"-csv",
+		"-c",
+		fmt.Sprintf("DESCRIBE %s;", TableName),
+	}
+
+	output, err := captureCommand(cmds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	return parseDescribeOutput(output)
+}
+
+// parseDescribeOutput parses the CSV output of DESCRIBE into columns
+func parseDescribeOutput(output []byte) ([]column, error) {
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("failed to parse schema: empty output")
+	}
+
+	var columns []column
+	for _, record := range records[1:] { // skip the header row
+		if len(record) < 3 {
+			return nil, fmt.Errorf("failed to parse schema: unexpected row %q", record)
+		}
+		columns = append(columns, column{Name: record[0], Type: record[1], Nullable: record[2] == "YES"})
+	}
+	return columns, nil
+}
+
+// printSchema prints the columns of the query in the requested format
+func printSchema(setup string, query string, format SchemaFormat) error {
+	if format == SchemaDuckDB {
+		cmds := []string{"duckdb", "-c", setup + "DESCRIBE " + query + ";"}
+		if err := executeCommand(cmds); err != nil {
+			return fmt.Errorf("failed to read schema: %w", err)
+		}
+		return nil
+	}
+
+	columns, err := describeQuery(setup, query)
+	if err != nil {
+		return err
+	}
+	output, err := formatSchema(columns, format)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, string(output))
+	return nil
+}
+
+// findColumn returns the column with the given name, or an error listing the available columns
+func findColumn(columns []column, name string) (column, error) {
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if c.Name == name {
+			return c, nil
+		}
+		names = append(names, c.Name)
+	}
+	return column{}, fmt.Errorf("column '%s' not found (available: %s)", name, strings.Join(names, ", "))
+}
+
+// checkRequiredColumns returns an error naming the required columns the input does not have. The
+// names have to match exactly, including their case.
+func checkRequiredColumns(input inputTable, required []string) error {
+	query, err := buildSelectQuery(input.filename(), input.fileFormat, input.opts)
+	if err != nil {
+		return err
+	}
+	columns, err := describeQuery(sessionSetup(input.fileFormat, input.opts), query)
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		present[c.Name] = true
+	}
+	var missing []string
+	for _, name := range required {
+		if !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s is missing %d of %d required column(s): %s", input.path, len(missing), len(required), strings.Join(missing, ", "))
+	}
+	fmt.Fprintf(os.Stdout, "%s has all %d required column(s)\n", input.path, len(required))
+	return nil
+}
+
+// quoteIdentifier quotes a column name so it can be used verbatim in a query
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes a value as a SQL string literal
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// projectionSelectList builds the select list of --columns, or of the first limit columns for
+// --limit-columns. The filename column of --with-filename is always kept.
+func projectionSelectList(columns []column, names []string, limit int, keepFilename bool) (string, error) {
+	var selected []string
+	if len(names) > 0 {
+		seen := make(map[string]bool, len(names))
+		for _, name := range names {
+			c, err := findColumn(columns, name)
+			if err != nil {
+				return "", fmt.Errorf("--columns: %w", err)
+			}
+			if seen[c.Name] {
+				return "", fmt.Errorf("--columns: column '%s' is listed twice", c.Name)
+			}
+			seen[c.Name] = true
+			selected = append(selected, c.Name)
+		}
+	} else {
+		for _, c := range columns {
+			if len(selected) == limit {
+				break
+			}
+			if keepFilename && c.Name == "filename" {
+				continue
+			}
+			selected = append(selected, c.Name)
+		}
+	}
+	if keepFilename && !slices.Contains(selected, "filename") {
+		selected = append(selected, "filename")
+	}
+
+	items := make([]string, 0, len(selected))
+	for _, name := range selected {
+		items = append(items, quoteIdentifier(name))
+	}
+	return strings.Join(items, ", "), nil
+}
+
+// excludeSelectList builds a select list of the columns in their order, leaving out the excluded ones.
+// Every excluded name must exist and at least one column must be left.
+func excludeSelectList(columns []column, excluded []string) (string, error) {
+	skip := make(map[string]bool, len(excluded))
+	for _, name := range excluded {
+		c, err := findColumn(columns, name)
+		if err != nil {
+			return "", fmt.Errorf("--exclude-columns: %w", err)
+		}
+		skip[c.Name] = true
+	}
+	items := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if !skip[c.Name] {
+			items = append(items, quoteIdentifier(c.Name))
+		}
+	}
+	if len(items) == 0 {
+		return "", fmt.Errorf("--exclude-columns leaves no columns to load")
+	}
+	return strings.Join(items, ", "), nil
+}
+
+// renameSelectList builds a select list aliasing the columns of the map to their new names and keeping
+// the others. Every old name must exist and no two columns may end up with the same name, which DuckDB
+// compares without regard to case.
+func renameSelectList(columns []column, renames map[string]string) (string, error) {
+	olds := make([]string, 0, len(renames))
+	for old := range renames {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+	for _, old := range olds {
+		if _, err := findColumn(columns, old); err != nil {
+			return "", fmt.Errorf("--rename-map: %w", err)
+		}
+	}
+
+	// Keyed by the lowercase new name, holding the old and new name of the column
+	seen := make(map[string][2]string, len(columns))
+	items := make([]string, 0, len(columns))
+	for _, c := range columns {
+		name, ok := renames[c.Name]
+		if !ok {
+			name = c.Name
+		}
+		if other, ok := seen[strings.ToLower(name)]; ok {
+			if other[1] != name {
+				return "", fmt.Errorf("--rename-map: columns %q and %q would be named %q and %q, which DuckDB treats as the same name",
+					other[0], c.Name, other[1], name)
+			}
+			return "", fmt.Errorf("--rename-map: columns %q and %q would both be named %q", other[0], c.Name, name)
+		}
+		seen[strings.ToLower(name)] = [2]string{c.Name, name}
+		items = append(items, fmt.Sprintf("%s AS %s", quoteIdentifier(c.Name), quoteIdentifier(name)))
+	}
+	return strings.Join(items, ", "), nil
+}
+
+// lowercaseSelectList builds a select list aliasing every column to its lowercase name.
+// Columns that differ only by case would end up with the same name, so they are rejected.
+func lowercaseSelectList(columns []column) (string, error) {
+	seen := make(map[string]string, len(columns))
+	items := make([]string, 0, len(columns))
+	for _, c := range columns {
+		lower := strings.ToLower(c.Name)
+		if other, ok := seen[lower]; ok {
+			return "", fmt.Errorf("columns %q and %q both lowercase to %q", other, c.Name, lower)
+		}
+		seen[lower] = c.Name
+		items = append(items, fmt.Sprintf("%s AS %s", quoteIdentifier(c.Name), quoteIdentifier(lower)))
+	}
+	return strings.Join(items, ", "), nil
+}
+
+// trimSelectList builds a select list that trims the spaces around the values of the VARCHAR
+// columns and keeps the other columns as they are
+func trimSelectList(columns []column) string {
+	var replaced []string
+	for _, c := range columns {
+		if strings.ToUpper(c.Type) == "VARCHAR" {
+			replaced = append(replaced, fmt.Sprintf("trim(%[1]s) AS %[1]s", quoteIdentifier(c.Name)))
+		}
+	}
+	if len(replaced) == 0 {
+		return "*"
+	}
+	return fmt.Sprintf("* REPLACE (%s)", strings.Join(replaced, ", "))
+}
+
+// flattenSelectList builds a select list replacing every STRUCT column with its fields, named
+// parent.field, down to depth levels of nesting. Lists and maps are kept as they are.
+func flattenSelectList(columns []column, depth int) (string, error) {
+	seen := make(map[string]bool, len(columns))
+	var items []string
+	var flatten func(expr string, name string, t *duckType, level int) error
+	flatten = func(expr string, name string, t *duckType, level int) error {
+		if t.Name == "STRUCT" && level < depth {
+			for _, f := range t.Fields {
+				// Bracket access cannot be mistaken for a table name like "a"."b" can
+				if err := flatten(expr+"["+quoteLiteral(f.Name)+"]", name+"."+f.Name, f.Type, level+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if seen[name] {
+			return fmt.Errorf("flattening gives two columns named %q", name)
+		}
+		seen[name] = true
+		items = append(items, fmt.Sprintf("%s AS %s", expr, quoteIdentifier(name)))
+		return nil
+	}
+	for _, c := range columns {
+		t, err := parseDuckType(c.Type)
+		if err != nil {
+			return "", fmt.Errorf("column %s: %w", c.Name, err)
+		}
+		if err := flatten(quoteIdentifier(c.Name), c.Name, t, 0); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(items, ", "), nil
+}
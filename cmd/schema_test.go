@@ -0,0 +1,57 @@
+package cmd
+
+import "testing"
+
+func TestTrimSelectList(t *testing.T) {
+	tests := []struct {
+		columns []column
+		want    string
+	}{
+		{nil, "*"},
+		{[]column{{Name: "id", Type: "INTEGER"}, {Name: "day", Type: "DATE"}}, "*"},
+		{[]column{{Name: "id", Type: "INTEGER"}, {Name: "name", Type: "VARCHAR"}}, `* REPLACE (trim("name") AS "name")`},
+		{[]column{{Name: "first name", Type: "VARCHAR"}, {Name: `a"b`, Type: "varchar"}},
+			`* REPLACE (trim("first name") AS "first name", trim("a""b") AS "a""b")`},
+		// Only plain text columns are trimmed, not lists or structs holding text
+		{[]column{{Name: "tags", Type: "VARCHAR[]"}, {Name: "s", Type: "STRUCT(v VARCHAR)"}}, "*"},
+	}
+	for _, tt := range tests {
+		if got := trimSelectList(tt.columns); got != tt.want {
+			t.Errorf("trimSelectList(%v) = %s, want %s", tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestRenameSelectList(t *testing.T) {
+	columns := []column{{Name: "a", Type: "INTEGER"}, {Name: "b", Type: "VARCHAR"}, {Name: "Day", Type: "DATE"}}
+
+	tests := []struct {
+		name    string
+		renames map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"none", nil, `"a" AS "a", "b" AS "b", "Day" AS "Day"`, false},
+		{"rename", map[string]string{"a": "id"}, `"a" AS "id", "b" AS "b", "Day" AS "Day"`, false},
+		{"change case", map[string]string{"Day": "day"}, `"a" AS "a", "b" AS "b", "Day" AS "day"`, false},
+		{"swap", map[string]string{"a": "b", "b": "a"}, `"a" AS "b", "b" AS "a", "Day" AS "Day"`, false},
+
+		{"unknown column", map[string]string{"c": "d"}, "", true},
+		{"unknown case", map[string]string{"day": "d"}, "", true},
+		{"same name", map[string]string{"a": "b"}, "", true},
+		{"same name in another case", map[string]string{"a": "B"}, "", true},
+		{"two renames in different cases", map[string]string{"a": "x", "b": "X"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renameSelectList(columns, tt.renames)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renameSelectList(%v) error = %v, wantErr %v", tt.renames, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("renameSelectList(%v) = %s, want %s", tt.renames, got, tt.want)
+			}
+		})
+	}
+}
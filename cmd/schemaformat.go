@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SchemaFormat is the representation used when printing the schema with --schema
+type SchemaFormat string
+
+const (
+	SchemaDuckDB     SchemaFormat = "duckdb"
+	SchemaArrow      SchemaFormat = "arrow"
+	SchemaJSONSchema SchemaFormat = "json-schema"
+)
+
+func parseSchemaFormat(s string) (SchemaFormat, error) {
+	switch f := SchemaFormat(strings.ToLower(s)); f {
+	case SchemaDuckDB, SchemaArrow, SchemaJSONSchema:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported schema format '%s' (expected %s, %s or %s)",
+			s, SchemaDuckDB, SchemaArrow, SchemaJSONSchema)
+	}
+}
+
+// formatSchema renders the columns in the given format. The duckdb format is printed by DuckDB itself
+// and is not handled here.
+func formatSchema(columns []column, format SchemaFormat) ([]byte, error) {
+	var doc any
+	var err error
+	switch format {
+	case SchemaArrow:
+		doc, err = arrowSchema(columns)
+	case SchemaJSONSchema:
+		doc, err = jsonSchema(columns)
+	default:
+		return nil, fmt.Errorf("unsupported schema format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// createTableDDL returns a CREATE TABLE statement declaring the columns with their DuckDB types, to
+// recreate the table elsewhere without reading the files
+func createTableDDL(table string, columns []column) string {
+	lines := make([]string, 0, len(columns))
+	for _, c := range columns {
+		line := fmt.Sprintf("    %s %s", quoteIdentifier(c.Name), c.Type)
+		if !c.Nullable {
+			line += " NOT NULL"
+		}
+		lines = append(lines, line)
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);", quoteIdentifier(table), strings.Join(lines, ",\n"))
+}
+
+// arrowSchema maps the columns to fields in the Arrow JSON schema representation
+func arrowSchema(columns []column) (map[string]any, error) {
+	fields := make([]arrowFieldJSON, 0, len(columns))
+	for _, c := range columns {
+		t, err := parseDuckType(c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", c.Name, err)
+		}
+		field, err := arrowField(c.Name, t, c.Nullable)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", c.Name, err)
+		}
+		fields = append(fields, field)
+	}
+	return map[string]any{"fields": fields}, nil
+}
+
+// arrowFieldJSON is a field in the Arrow JSON schema representation
+type arrowFieldJSON struct {
+	Name       string           `json:"name"`
+	Nullable   bool             `json:"nullable"`
+	Type       map[string]any   `json:"type"`
+	Dictionary map[string]any   `json:"dictionary,omitempty"`
+	Children   []arrowFieldJSON `json:"children"`
+}
+
+func arrowField(name string, t *duckType, nullable bool) (arrowFieldJSON, error) {
+	field := arrowFieldJSON{Name: name, Nullable: nullable, Children: []arrowFieldJSON{}}
+
+	arrowInt := func(bits int, signed bool) map[string]any {
+		return map[string]any{"name": "int", "bitWidth": bits, "isSigned": signed}
+	}
+	arrowTimestamp := func(unit string) map[string]any {
+		return map[string]any{"name": "timestamp", "unit": unit}
+	}
+
+	switch t.Name {
+	case "BOOLEAN", "BOOL":
+		field.Type = map[string]any{"name": "bool"}
+	case "TINYINT", "INT1":
+		field.Type = arrowInt(8, true)
+	case "SMALLINT", "INT2":
+		field.Type = arrowInt(16, true)
+	case "INTEGER", "INT", "INT4":
+		field.Type = arrowInt(32, true)
+	case "BIGINT", "INT8":
+		field.Type = arrowInt(64, true)
+	case "UTINYINT":
+		field.Type = arrowInt(8, false)
+	case "USMALLINT":
+		field.Type = arrowInt(16, false)
+	case "UINTEGER":
+		field.Type = arrowInt(32, false)
+	case "UBIGINT":
+		field.Type = arrowInt(64, false)
+	case "HUGEINT", "UHUGEINT":
+		// Arrow has no 128-bit integer; DuckDB exports these as DECIMAL(38,0)
+		field.Type = map[string]any{"name": "decimal", "precision": 38, "scale": 0, "bitWidth": 128}
+	case "FLOAT", "REAL":
+		field.Type = map[string]any{"name": "floatingpoint", "precision": "SINGLE"}
+	case "DOUBLE":
+		field.Type = map[string]any{"name": "floatingpoint", "precision": "DOUBLE"}
+	case "DECIMAL", "NUMERIC":
+		precision, scale, err := decimalArgs(t)
+		if err != nil {
+			return arrowFieldJSON{}, err
+		}
+		field.Type = map[string]any{"name": "decimal", "precision": precision, "scale": scale, "bitWidth": 128}
+	case "VARCHAR", "JSON", "UUID", "BIT":
+		// DuckDB exports these as plain strings unless lossless conversion is enabled
+		field.Type = map[string]any{"name": "utf8"}
+	case "BLOB":
+		field.Type = map[string]any{"name": "binary"}
+	case "DATE":
+		field.Type = map[string]any{"name": "date", "unit": "DAY"}
+	case "TIME", "TIME WITH TIME ZONE":
+		field.Type = map[string]any{"name": "time", "unit": "MICROSECOND", "bitWidth": 64}
+	case "TIMESTAMP":
+		field.Type = arrowTimestamp("MICROSECOND")
+	case "TIMESTAMP_S":
+		field.Type = arrowTimestamp("SECOND")
+	case "TIMESTAMP_MS":
+		field.Type = arrowTimestamp("MILLISECOND")
+	case "TIMESTAMP_NS":
+		field.Type = arrowTimestamp("NANOSECOND")
+	case "TIMESTAMP WITH TIME ZONE":
+		ts := arrowTimestamp("MICROSECOND")
+		ts["timezone"] = "UTC"
+		field.Type = ts
+	case "INTERVAL":
+		field.Type = map[string]any{"name": "interval", "unit": "MONTH_DAY_NANO"}
+	case "ENUM":
+		field.Type = map[string]any{"name": "utf8"}
+		field.Dictionary = map[string]any{"id": 0, "indexType": arrowInt(32, true), "isOrdered": false}
+	case "LIST":
+		child, err := arrowField("item", t.Elem, true)
+		if err != nil {
+			return arrowFieldJSON{}, err
+		}
+		field.Type = map[string]any{"name": "list"}
+		field.Children = []arrowFieldJSON{child}
+	case "ARRAY":
+		child, err := arrowField("item", t.Elem, true)
+		if err != nil {
+			return arrowFieldJSON{}, err
+		}
+		field.Type = map[string]any{"name": "fixedsizelist", "listSize": t.Size}
+		field.Children = []arrowFieldJSON{child}
+	case "STRUCT", "UNION":
+		children := make([]arrowFieldJSON, 0, len(t.Fields))
+		typeIDs := make([]int, 0, len(t.Fields))
+		for i, f := range t.Fields {
+			child, err := arrowField(f.Name, f.Type, true)
+			if err != nil {
+				return arrowFieldJSON{}, err
+			}
+			children = append(children, child)
+			typeIDs = append(typeIDs, i)
+		}
+		if t.Name == "STRUCT" {
+			field.Type = map[string]any{"name": "struct"}
+		} else {
+			field.Type = map[string]any{"name": "union", "mode": "SPARSE", "typeIds": typeIDs}
+		}
+		field.Children = children
+	case "MAP":
+		key, err := arrowField("key", t.Fields[0].Type, false)
+		if err != nil {
+			return arrowFieldJSON{}, err
+		}
+		value, err := arrowField("value", t.Fields[1].Type, true)
+		if err != nil {
+			return arrowFieldJSON{}, err
+		}
+		entries := arrowFieldJSON{
+			Name:     "entries",
+			Nullable: false,
+			Type:     map[string]any{"name": "struct"},
+			Children: []arrowFieldJSON{key, value},
+		}
+		field.Type = map[string]any{"name": "map", "keysSorted": false}
+		field.Children = []arrowFieldJSON{entries}
+	default:
+		return arrowFieldJSON{}, fmt.Errorf("no Arrow mapping for type %s", t.Name)
+	}
+	return field, nil
+}
+
+// jsonSchema maps the columns to a JSON Schema (draft 2020-12) describing one row as an object
+func jsonSchema(columns []column) (map[string]any, error) {
+	properties := make(map[string]any, len(columns))
+	var required []string
+	for _, c := range columns {
+		t, err := parseDuckType(c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", c.Name, err)
+		}
+		prop, err := jsonSchemaType(t)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", c.Name, err)
+		}
+		if c.Nullable {
+			prop = map[string]any{"anyOf": []any{prop, map[string]any{"type": "null"}}}
+		} else {
+			required = append(required, c.Name)
+		}
+		properties[c.Name] = prop
+	}
+
+	schema := map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      TableName,
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+func jsonSchemaType(t *duckType) (map[string]any, error) {
+	str := func(format string) map[string]any {
+		return map[string]any{"type": "string", "format": format}
+	}
+
+	switch t.Name {
+	case "BOOLEAN", "BOOL":
+		return map[string]any{"type": "boolean"}, nil
+	case "TINYINT", "INT1", "SMALLINT", "INT2", "INTEGER", "INT", "INT4", "BIGINT", "INT8", "HUGEINT":
+		return map[string]any{"type": "integer"}, nil
+	case "UTINYINT", "USMALLINT", "UINTEGER", "UBIGINT", "UHUGEINT":
+		return map[string]any{"type": "integer", "minimum": 0}, nil
+	case "FLOAT", "REAL", "DOUBLE", "DECIMAL", "NUMERIC":
+		return map[string]any{"type": "number"}, nil
+	case "VARCHAR", "BIT":
+		return map[string]any{"type": "string"}, nil
+	case "BLOB":
+		return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+	case "UUID":
+		return str("uuid"), nil
+	case "DATE":
+		return str("date"), nil
+	case "TIME", "TIME WITH TIME ZONE":
+		return str("time"), nil
+	case "TIMESTAMP", "TIMESTAMP_S", "TIMESTAMP_MS", "TIMESTAMP_NS", "TIMESTAMP WITH TIME ZONE":
+		return str("date-time"), nil
+	case "INTERVAL":
+		return str("duration"), nil
+	case "JSON":
+		return map[string]any{}, nil // any JSON value
+	case "ENUM":
+		values := make([]string, 0, len(t.Args))
+		for _, a := range t.Args {
+			values = append(values, unquoteLiteral(a))
+		}
+		return map[string]any{"type": "string", "enum": values}, nil
+	case "LIST", "ARRAY":
+		items, err := jsonSchemaType(t.Elem)
+		if err != nil {
+			return nil, err
+		}
+		arr := map[string]any{"type": "array", "items": items}
+		if t.Name == "ARRAY" {
+			arr["minItems"] = t.Size
+			arr["maxItems"] = t.Size
+		}
+		return arr, nil
+	case "STRUCT":
+		properties := make(map[string]any, len(t.Fields))
+		for _, f := range t.Fields {
+			prop, err := jsonSchemaType(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			properties[f.Name] = prop
+		}
+		return map[string]any{"type": "object", "properties": properties}, nil
+	case "MAP":
+		// JSON object keys are always strings, so only the value type is described
+		value, err := jsonSchemaType(t.Fields[1].Type)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": value}, nil
+	case "UNION":
+		members := make([]any, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			m, err := jsonSchemaType(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, m)
+		}
+		return map[string]any{"anyOf": members}, nil
+	default:
+		return nil, fmt.Errorf("no JSON Schema mapping for type %s", t.Name)
+	}
+}
+
+// decimalArgs returns the precision and scale of a DECIMAL type, defaulting to DuckDB's DECIMAL(18,3)
+func decimalArgs(t *duckType) (int, int, error) {
+	if len(t.Args) == 0 {
+		return 18, 3, nil
+	}
+	if len(t.Args) != 2 {
+		return 0, 0, fmt.Errorf("invalid DECIMAL parameters %v", t.Args)
+	}
+	precision, err := strconv.Atoi(t.Args[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid DECIMAL precision %q", t.Args[0])
+	}
+	scale, err := strconv.Atoi(t.Args[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid DECIMAL scale %q", t.Args[1])
+	}
+	return precision, scale, nil
+}
+
+// structureTree renders the columns as an indented tree with one line per field, nested STRUCT,
+// UNION and MAP members indented below their parent. Lists keep their element's members, so a list
+// of objects shows the fields of the objects.
+func structureTree(columns []column) (string, error) {
+	var b strings.Builder
+	for _, c := range columns {
+		t, err := parseDuckType(c.Type)
+		if err != nil {
+			return "", fmt.Errorf("column %q: %w", c.Name, err)
+		}
+		writeStructure(&b, c.Name, t, 0)
+	}
+	return b.String(), nil
+}
+
+// writeStructure writes the field and its members at the given depth
+func writeStructure(b *strings.Builder, name string, t *duckType, depth int) {
+	fmt.Fprintf(b, "%s%s %s\n", strings.Repeat("  ", depth), name, structureLabel(t))
+	for t.Elem != nil {
+		t = t.Elem
+	}
+	for _, f := range t.Fields {
+		writeStructure(b, f.Name, f.Type, depth+1)
+	}
+}
+
+// structureLabel names the type of a tree line, nested types by their kind alone since their members
+// follow on the lines below, e.g. STRUCT[] for a list of objects
+func structureLabel(t *duckType) string {
+	switch {
+	case t.Name == "LIST":
+		return structureLabel(t.Elem) + "[]"
+	case t.Name == "ARRAY":
+		return fmt.Sprintf("%s[%d]", structureLabel(t.Elem), t.Size)
+	case len(t.Args) > 0:
+		return fmt.Sprintf("%s(%s)", t.Name, strings.Join(t.Args, ", "))
+	default:
+		return t.Name
+	}
+}
@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitTopLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"INTEGER", []string{"INTEGER"}},
+		{"a INTEGER, b VARCHAR", []string{"a INTEGER", "b VARCHAR"}},
+		{"18, 3", []string{"18", "3"}},
+		{"a STRUCT(x INTEGER, y DOUBLE), b INTEGER[]", []string{"a STRUCT(x INTEGER, y DOUBLE)", "b INTEGER[]"}},
+		{"VARCHAR, MAP(VARCHAR, INTEGER[])", []string{"VARCHAR", "MAP(VARCHAR, INTEGER[])"}},
+		{"a DECIMAL(10, 2), b STRUCT(c DECIMAL(4,1))", []string{"a DECIMAL(10, 2)", "b STRUCT(c DECIMAL(4,1))"}},
+		{`"a,b" INTEGER, c VARCHAR`, []string{`"a,b" INTEGER`, "c VARCHAR"}},
+		{"'x,y', 'z'", []string{"'x,y'", "'z'"}},
+		{" a INTEGER , b VARCHAR ", []string{"a INTEGER", "b VARCHAR"}},
+	}
+	for _, tt := range tests {
+		if got := splitTopLevel(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitTopLevel(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuckType(t *testing.T) {
+	integer := &duckType{Name: "INTEGER"}
+	varchar := &duckType{Name: "VARCHAR"}
+	tests := []struct {
+		in   string
+		want *duckType
+	}{
+		{"INTEGER", integer},
+		{" varchar ", varchar},
+		{"TIMESTAMP WITH TIME ZONE", &duckType{Name: "TIMESTAMP WITH TIME ZONE"}},
+		{"DECIMAL(18,3)", &duckType{Name: "DECIMAL", Args: []string{"18", "3"}}},
+		{"ENUM('a', 'b,c')", &duckType{Name: "ENUM", Args: []string{"'a'", "'b,c'"}}},
+		{"INTEGER[]", &duckType{Name: "LIST", Elem: integer}},
+		{"INTEGER[3]", &duckType{Name: "ARRAY", Elem: integer, Size: 3}},
+		{"VARCHAR[][]", &duckType{Name: "LIST", Elem: &duckType{Name: "LIST", Elem: varchar}}},
+		{"STRUCT(a INTEGER, \"b c\" VARCHAR[])", &duckType{Name: "STRUCT", Fields: []typeField{
+			{Name: "a", Type: integer},
+			{Name: "b c", Type: &duckType{Name: "LIST", Elem: varchar}},
+		}}},
+		{"STRUCT(a STRUCT(b DECIMAL(4,1)))[]", &duckType{Name: "LIST", Elem: &duckType{Name: "STRUCT", Fields: []typeField{
+			{Name: "a", Type: &duckType{Name: "STRUCT", Fields: []typeField{
+				{Name: "b", Type: &duckType{Name: "DECIMAL", Args: []string{"4", "1"}}},
+			}}},
+		}}}},
+		{"MAP(VARCHAR, INTEGER[])", &duckType{Name: "MAP", Fields: []typeField{
+			{Name: "key", Type: varchar},
+			{Name: "value", Type: &duckType{Name: "LIST", Elem: integer}},
+		}}},
+		{"UNION(num INTEGER, str VARCHAR)", &duckType{Name: "UNION", Fields: []typeField{
+			{Name: "num", Type: integer},
+			{Name: "str", Type: varchar},
+		}}},
+	}
+	for _, tt := range tests {
+		got, err := parseDuckType(tt.in)
+		if err != nil {
+			t.Errorf("parseDuckType(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseDuckType(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuckTypeErrors(t *testing.T) {
+	for _, in := range []string{"", "[]", "INTEGER[x]", "DECIMAL(18,3", "MAP(VARCHAR)", "STRUCT(a)", `STRUCT("a INTEGER)`} {
+		if _, err := parseDuckType(in); err == nil {
+			t.Errorf("parseDuckType(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+// mustParseType parses a type of the test tables, which are all valid
+func mustParseType(t *testing.T, s string) *duckType {
+	t.Helper()
+	dt, err := parseDuckType(s)
+	if err != nil {
+		t.Fatalf("parseDuckType(%q) failed: %v", s, err)
+	}
+	return dt
+}
+
+// compactJSON marshals v, whose map keys encoding/json sorts, so it can be compared as a string
+func compactJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestArrowFieldTypes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"BOOLEAN", `{"name":"bool"}`},
+		{"BOOL", `{"name":"bool"}`},
+		{"TINYINT", `{"bitWidth":8,"isSigned":true,"name":"int"}`},
+		{"INT1", `{"bitWidth":8,"isSigned":true,"name":"int"}`},
+		{"SMALLINT", `{"bitWidth":16,"isSigned":true,"name":"int"}`},
+		{"INT2", `{"bitWidth":16,"isSigned":true,"name":"int"}`},
+		{"INTEGER", `{"bitWidth":32,"isSigned":true,"name":"int"}`},
+		{"INT", `{"bitWidth":32,"isSigned":true,"name":"int"}`},
+		{"INT4", `{"bitWidth":32,"isSigned":true,"name":"int"}`},
+		{"BIGINT", `{"bitWidth":64,"isSigned":true,"name":"int"}`},
+		{"INT8", `{"bitWidth":64,"isSigned":true,"name":"int"}`},
+		{"UTINYINT", `{"bitWidth":8,"isSigned":false,"name":"int"}`},
+		{"USMALLINT", `{"bitWidth":16,"isSigned":false,"name":"int"}`},
+		{"UINTEGER", `{"bitWidth":32,"isSigned":false,"name":"int"}`},
+		{"UBIGINT", `{"bitWidth":64,"isSigned":false,"name":"int"}`},
+		{"HUGEINT", `{"bitWidth":128,"name":"decimal","precision":38,"scale":0}`},
+		{"UHUGEINT", `{"bitWidth":128,"name":"decimal","precision":38,"scale":0}`},
+		{"FLOAT", `{"name":"floatingpoint","precision":"SINGLE"}`},
+		{"REAL", `{"name":"floatingpoint","precision":"SINGLE"}`},
+		{"DOUBLE", `{"name":"floatingpoint","precision":"DOUBLE"}`},
+		{"DECIMAL(10,2)", `{"bitWidth":128,"name":"decimal","precision":10,"scale":2}`},
+		{"NUMERIC", `{"bitWidth":128,"name":"decimal","precision":18,"scale":3}`},
+		{"VARCHAR", `{"name":"utf8"}`},
+		{"JSON", `{"name":"utf8"}`},
+		{"UUID", `{"name":"utf8"}`},
+		{"BIT", `{"name":"utf8"}`},
+		{"BLOB", `{"name":"binary"}`},
+		{"DATE", `{"name":"date","unit":"DAY"}`},
+		{"TIME", `{"bitWidth":64,"name":"time","unit":"MICROSECOND"}`},
+		{"TIME WITH TIME ZONE", `{"bitWidth":64,"name":"time","unit":"MICROSECOND"}`},
+		{"TIMESTAMP", `{"name":"timestamp","unit":"MICROSECOND"}`},
+		{"TIMESTAMP_S", `{"name":"timestamp","unit":"SECOND"}`},
+		{"TIMESTAMP_MS", `{"name":"timestamp","unit":"MILLISECOND"}`},
+		{"TIMESTAMP_NS", `{"name":"timestamp","unit":"NANOSECOND"}`},
+		{"TIMESTAMP WITH TIME ZONE", `{"name":"timestamp","timezone":"UTC","unit":"MICROSECOND"}`},
+		{"INTERVAL", `{"name":"interval","unit":"MONTH_DAY_NANO"}`},
+		{"ENUM('a', 'b')", `{"name":"utf8"}`},
+		{"INTEGER[]", `{"name":"list"}`},
+		{"INTEGER[2]", `{"listSize":2,"name":"fixedsizelist"}`},
+		{"STRUCT(a INTEGER)", `{"name":"struct"}`},
+		{"UNION(a INTEGER, b VARCHAR)", `{"mode":"SPARSE","name":"union","typeIds":[0,1]}`},
+		{"MAP(VARCHAR, INTEGER)", `{"keysSorted":false,"name":"map"}`},
+	}
+	for _, tt := range tests {
+		field, err := arrowField("c", mustParseType(t, tt.in), true)
+		if err != nil {
+			t.Errorf("arrowField(%s) failed: %v", tt.in, err)
+			continue
+		}
+		if got := compactJSON(t, field.Type); got != tt.want {
+			t.Errorf("arrowField(%s) type = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestArrowFieldChildren(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"ENUM('a')", `{"name":"c","nullable":true,"type":{"name":"utf8"},"dictionary":{"id":0,"indexType":{"bitWidth":32,"isSigned":true,"name":"int"},"isOrdered":false},"children":[]}`},
+		{"VARCHAR[]", `{"name":"c","nullable":true,"type":{"name":"list"},"children":[{"name":"item","nullable":true,"type":{"name":"utf8"},"children":[]}]}`},
+		{"STRUCT(a INTEGER, b BOOLEAN)", `{"name":"c","nullable":true,"type":{"name":"struct"},"children":[` +
+			`{"name":"a","nullable":true,"type":{"bitWidth":32,"isSigned":true,"name":"int"},"children":[]},` +
+			`{"name":"b","nullable":true,"type":{"name":"bool"},"children":[]}]}`},
+		{"MAP(VARCHAR, DOUBLE)", `{"name":"c","nullable":true,"type":{"keysSorted":false,"name":"map"},"children":[` +
+			`{"name":"entries","nullable":false,"type":{"name":"struct"},"children":[` +
+			`{"name":"key","nullable":false,"type":{"name":"utf8"},"children":[]},` +
+			`{"name":"value","nullable":true,"type":{"name":"floatingpoint","precision":"DOUBLE"},"children":[]}]}]}`},
+	}
+	for _, tt := range tests {
+		field, err := arrowField("c", mustParseType(t, tt.in), true)
+		if err != nil {
+			t.Errorf("arrowField(%s) failed: %v", tt.in, err)
+			continue
+		}
+		if got := compactJSON(t, field); got != tt.want {
+			t.Errorf("arrowField(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJSONSchemaTypes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"BOOLEAN", `{"type":"boolean"}`},
+		{"BOOL", `{"type":"boolean"}`},
+		{"TINYINT", `{"type":"integer"}`},
+		{"INT1", `{"type":"integer"}`},
+		{"SMALLINT", `{"type":"integer"}`},
+		{"INT2", `{"type":"integer"}`},
+		{"INTEGER", `{"type":"integer"}`},
+		{"INT", `{"type":"integer"}`},
+		{"INT4", `{"type":"integer"}`},
+		{"BIGINT", `{"type":"integer"}`},
+		{"INT8", `{"type":"integer"}`},
+		{"HUGEINT", `{"type":"integer"}`},
+		{"UTINYINT", `{"minimum":0,"type":"integer"}`},
+		{"USMALLINT", `{"minimum":0,"type":"integer"}`},
+		{"UINTEGER", `{"minimum":0,"type":"integer"}`},
+		{"UBIGINT", `{"minimum":0,"type":"integer"}`},
+		{"UHUGEINT", `{"minimum":0,"type":"integer"}`},
+		{"FLOAT", `{"type":"number"}`},
+		{"REAL", `{"type":"number"}`},
+		{"DOUBLE", `{"type":"number"}`},
+		{"DECIMAL(10,2)", `{"type":"number"}`},
+		{"NUMERIC", `{"type":"number"}`},
+		{"VARCHAR", `{"type":"string"}`},
+		{"BIT", `{"type":"string"}`},
+		{"BLOB", `{"contentEncoding":"base64","type":"string"}`},
+		{"UUID", `{"format":"uuid","type":"string"}`},
+		{"DATE", `{"format":"date","type":"string"}`},
+		{"TIME", `{"format":"time","type":"string"}`},
+		{"TIME WITH TIME ZONE", `{"format":"time","type":"string"}`},
+		{"TIMESTAMP", `{"format":"date-time","type":"string"}`},
+		{"TIMESTAMP_S", `{"format":"date-time","type":"string"}`},
+		{"TIMESTAMP_MS", `{"format":"date-time","type":"string"}`},
+		{"TIMESTAMP_NS", `{"format":"date-time","type":"string"}`},
+		{"TIMESTAMP WITH TIME ZONE", `{"format":"date-time","type":"string"}`},
+		{"INTERVAL", `{"format":"duration","type":"string"}`},
+		{"JSON", `{}`},
+		{"ENUM('a', 'it''s')", `{"enum":["a","it's"],"type":"string"}`},
+		{"INTEGER[]", `{"items":{"type":"integer"},"type":"array"}`},
+		{"DOUBLE[3]", `{"items":{"type":"number"},"maxItems":3,"minItems":3,"type":"array"}`},
+		{"STRUCT(a INTEGER, b VARCHAR[])", `{"properties":{"a":{"type":"integer"},"b":{"items":{"type":"string"},"type":"array"}},"type":"object"}`},
+		{"MAP(VARCHAR, DATE)", `{"additionalProperties":{"format":"date","type":"string"},"type":"object"}`},
+		{"UNION(a INTEGER, b VARCHAR)", `{"anyOf":[{"type":"integer"},{"type":"string"}]}`},
+	}
+	for _, tt := range tests {
+		schema, err := jsonSchemaType(mustParseType(t, tt.in))
+		if err != nil {
+			t.Errorf("jsonSchemaType(%s) failed: %v", tt.in, err)
+			continue
+		}
+		if got := compactJSON(t, schema); got != tt.want {
+			t.Errorf("jsonSchemaType(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSchemaMappingUnknownType(t *testing.T) {
+	for _, in := range []string{"GEOMETRY", "STRUCT(a GEOMETRY)", "GEOMETRY[]"} {
+		dt := mustParseType(t, in)
+		if _, err := arrowField("c", dt, true); err == nil || !strings.Contains(err.Error(), "GEOMETRY") {
+			t.Errorf("arrowField(%s) error = %v, want one naming GEOMETRY", in, err)
+		}
+		if _, err := jsonSchemaType(dt); err == nil || !strings.Contains(err.Error(), "GEOMETRY") {
+			t.Errorf("jsonSchemaType(%s) error = %v, want one naming GEOMETRY", in, err)
+		}
+	}
+}
+
+func TestJSONSchemaNullable(t *testing.T) {
+	schema, err := jsonSchema([]column{{Name: "id", Type: "BIGINT"}, {Name: "name", Type: "VARCHAR", Nullable: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"id":{"type":"integer"},` +
+		`"name":{"anyOf":[{"type":"string"},{"type":"null"}]}},"required":["id"],"title":"p","type":"object"}`
+	if got := compactJSON(t, schema); got != want {
+		t.Errorf("jsonSchema = %s, want %s", got, want)
+	}
+}
+
+func TestStructureTree(t *testing.T) {
+	got, err := structureTree([]column{
+		{Name: "id", Type: "INTEGER"},
+		{Name: "price", Type: "DECIMAL(10,2)"},
+		{Name: "items", Type: "STRUCT(sku VARCHAR, tags VARCHAR[])[]"},
+		{Name: "attrs", Type: "MAP(VARCHAR, INTEGER)"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `id INTEGER
+price DECIMAL(10, 2)
+items STRUCT[]
+  sku VARCHAR
+  tags VARCHAR[]
+attrs MAP
+  key VARCHAR
+  value INTEGER
+`
+	if got != want {
+		t.Errorf("structureTree =\n%s\nwant\n%s", got, want)
+	}
+}
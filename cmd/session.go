@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// writeInitFile writes a duckdb CLI init script with the given commands and returns its path.
+// Passing -init replaces ~/.duckdbrc, so the user's own init file is read first to keep their settings.
+func writeInitFile(tempDir string, commands []string) (string, error) {
+	var lines []string
+	if home, err := os.UserHomeDir(); err == nil {
+		if rc := filepath.Join(home, ".duckdbrc"); fileExists(rc) {
+			lines = append(lines, ".read "+rc)
+		}
+	}
+	lines = append(lines, commands...)
+
+	path := filepath.Join(tempDir, "init.sql")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write init file: %w", err)
+	}
+	return path, nil
+}
+
+// promptCommand returns the init script command setting the DuckDB CLI prompt. The prompt defaults to
+// the base name of the input followed by DuckDB's own "D ", so several open sessions can be told apart.
+func promptCommand(cmd *cobra.Command, input string) string {
+	prompt := filepath.Base(input) + " D "
+	if flagGiven(cmd, "prompt") {
+		prompt = cmd.Flag("prompt").Value.String()
+	}
+	// The DuckDB CLI reads quoted dot command arguments with C-style backslash escapes
+	prompt = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(prompt)
+	return fmt.Sprintf(`.prompt "%s"`, prompt)
+}
+
+// tableBanner returns the init script commands printing the tables with their column and row counts
+// when the DuckDB CLI starts, followed by an example query
+func tableBanner(duckdbPath string) ([]string, error) {
+	output, err := captureCommand([]string{"duckdb", duckdbPath, "-csv", "-noheader", "-c",
+		"SELECT table_name, column_count, estimated_size FROM duckdb_tables() WHERE schema_name = current_schema() ORDER BY table_name;"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the tables: %w", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the tables: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	var lines []string
+	for _, r := range records {
+		if len(r) != 3 {
+			return nil, fmt.Errorf("unexpected table row: %v", r)
+		}
+		lines = append(lines, fmt.Sprintf(".print Table %s: %s row(s), %s column(s)", r[0], r[2], r[1]))
+	}
+	lines = append(lines, fmt.Sprintf(".print Try: SELECT * FROM %s LIMIT 10;", records[0][0]), ".print")
+	return lines, nil
+}
+
+// outputWidth returns the width the DuckDB output should fit in: the explicit --width, or the width
+// of the terminal when stdout is one. Zero means DuckDB's defaults are kept.
+func outputWidth(width int) int {
+	if width > 0 {
+		return width
+	}
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0
+	}
+	if w, _, err := term.GetSize(fd); err == nil {
+		return w
+	}
+	return 0
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// settingName matches the names of DuckDB settings, which are plain identifiers
+var settingName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseSettings turns the key=value pairs of --set into SET statements. The value is always passed as
+// a string literal, DuckDB converts it to the type of the setting.
+func parseSettings(pairs []string) (string, error) {
+	var statements string
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !settingName.MatchString(key) {
+			return "", fmt.Errorf("invalid --set '%s': expected key=value with a setting name like memory_limit", pair)
+		}
+		statements += fmt.Sprintf("SET %s = %s; ", key, quoteLiteral(strings.TrimSpace(value)))
+	}
+	return statements, nil
+}
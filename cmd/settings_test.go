@@ -0,0 +1,42 @@
+package cmd
+
+import "testing"
+
+func TestParseSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    string
+		wantErr bool
+	}{
+		{"none", nil, "", false},
+		{"size", []string{"memory_limit=4GB"}, "SET memory_limit = '4GB'; ", false},
+		{"several", []string{"threads=4", "preserve_insertion_order=false"},
+			"SET threads = '4'; SET preserve_insertion_order = 'false'; ", false},
+		{"spaces around", []string{" threads = 4 "}, "SET threads = '4'; ", false},
+		{"quote in value", []string{"temp_directory=/tmp/it's"}, "SET temp_directory = '/tmp/it''s'; ", false},
+		{"injection in value", []string{"threads=1'; DROP TABLE p; --"}, "SET threads = '1''; DROP TABLE p; --'; ", false},
+		{"equals in value", []string{"search_path=a=b"}, "SET search_path = 'a=b'; ", false},
+		{"empty value", []string{"search_path="}, "SET search_path = ''; ", false},
+
+		{"statement in key", []string{"a;b=1"}, "", true},
+		{"empty key", []string{"=x"}, "", true},
+		{"key with spaces", []string{"memory limit=4GB"}, "", true},
+		{"key starting with a digit", []string{"1threads=4"}, "", true},
+		{"quoted key", []string{"'threads'=4"}, "", true},
+		{"no value", []string{"threads"}, "", true},
+		{"invalid after valid", []string{"threads=4", "a b=1"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSettings(tt.pairs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSettings(%q) error = %v, wantErr %v", tt.pairs, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSettings(%q) = %q, want %q", tt.pairs, got, tt.want)
+			}
+		})
+	}
+}
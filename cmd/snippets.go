@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const snippetExt = ".sql"
+
+var snippetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var snippetsCmd = &cobra.Command{
+	Use:   "snippets",
+	Short: "List the SQL snippets available to --run",
+	Long: `List the SQL snippets available to --run.
+
+Snippets are .sql files stored in $XDG_CONFIG_HOME/dpi/snippets (~/.config/dpi/snippets by default).
+A snippet is invoked by its file name without the extension and runs against table "p".`,
+	Args: cobra.NoArgs,
+	Run:  runSnippetsCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(snippetsCmd)
+}
+
+// configDir returns the dpi configuration directory, honoring XDG_CONFIG_HOME
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "dpi"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "dpi"), nil
+}
+
+func snippetsDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snippets"), nil
+}
+
+// findSnippet validates the snippet name and returns the path of its .sql file
+func findSnippet(name string) (string, error) {
+	if !snippetNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid snippet name '%s': only letters, digits, '-' and '_' are allowed", name)
+	}
+	dir, err := snippetsDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name+snippetExt)
+	if !fileExists(path) {
+		return "", fmt.Errorf("snippet not found: %s (looked in %s, see 'dpi snippets')", name, dir)
+	}
+	return path, nil
+}
+
+// listSnippets returns the names of all snippets in the snippets directory, sorted
+func listSnippets() ([]string, error) {
+	dir, err := snippetsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snippets directory: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), snippetExt)
+		if e.IsDir() || !ok || !snippetNamePattern.MatchString(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// snippetDescription returns the first line of the snippet if it is a SQL comment
+func snippetDescription(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if scanner.Scan() {
+		if desc, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "--"); ok {
+			return strings.TrimSpace(desc)
+		}
+	}
+	return ""
+}
+
+// runSnippet executes the snippet file against the database non-interactively
+func runSnippet(duckdbPath string, snippetPath string, outputArgs []string) error {
+	cmds := append([]string{"duckdb", duckdbPath}, outputArgs...)
+	cmds = append(cmds, "-f", snippetPath)
+	if err := executeCommand(cmds); err != nil {
+		return fmt.Errorf("failed to run snippet: %w", err)
+	}
+	return nil
+}
+
+func runSnippetsCommand(cmd *cobra.Command, args []string) {
+	dir, err := snippetsDir()
+	if err != nil {
+		exitWithError("%v", err)
+	}
+	names, err := listSnippets()
+	if err != nil {
+		exitWithError("%v", err)
+	}
+	if len(names) == 0 {
+		fmt.Fprintf(os.Stdout, "No snippets found in %s\n", dir)
+		return
+	}
+
+	for _, name := range names {
+		if desc := snippetDescription(filepath.Join(dir, name+snippetExt)); desc != "" {
+			fmt.Fprintf(os.Stdout, "%-20s %s\n", name, desc)
+		} else {
+			fmt.Fprintln(os.Stdout, name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// createTempDirectory creates the directory holding the temporary database and file copies. An explicit
+// --temp-dir is used as it is. Otherwise the system temporary directory is tried first, then the current
+// directory, so a full or read-only /tmp does not stop dpi from starting.
+func createTempDirectory(preferred string) (string, error) {
+	if preferred != "" {
+		return os.MkdirTemp(preferred, "dpi")
+	}
+
+	var errs []error
+	for _, dir := range tempDirCandidates() {
+		tempDir, err := os.MkdirTemp(dir, "dpi")
+		if err == nil {
+			if len(errs) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: cannot create a temporary directory (%v), using %s instead\n", errors.Join(errs...), tempDir)
+			}
+			return tempDir, nil
+		}
+		errs = append(errs, err)
+	}
+	return "", fmt.Errorf("%w (use --temp-dir to choose a directory)", errors.Join(errs...))
+}
+
+// tempDirCandidates returns the directories createTempDirectory tries, in order
+func tempDirCandidates() []string {
+	candidates := []string{os.TempDir()}
+	if cwd, err := os.Getwd(); err == nil && filepath.Clean(cwd) != filepath.Clean(candidates[0]) {
+		candidates = append(candidates, cwd)
+	}
+	return candidates
+}
+
+// estimateTableSize returns a rough size of the table the inputs load into: the size of the local
+// input files. Remote inputs and table directories are not counted.
+func estimateTableSize(inputs []inputTable) int64 {
+	var size int64
+	for _, input := range inputs {
+		if input.opts.remote {
+			continue
+		}
+		for _, f := range input.files {
+			if info, err := os.Stat(f); err == nil && info.Mode().IsRegular() {
+				size += info.Size()
+			}
+		}
+	}
+	return size
+}
+
+// estimateTableSizeFromMetadata estimates the size of the table more closely than estimateTableSize
+// for --estimate: Parquet files, local or remote, count with their uncompressed size from the
+// footers, other files with their file size
+func estimateTableSizeFromMetadata(inputs []inputTable) (int64, error) {
+	var size int64
+	for _, input := range inputs {
+		if input.fileFormat != Parquet {
+			size += estimateTableSize([]inputTable{input})
+			continue
+		}
+		n, err := parquetUncompressedSize(sessionSetup(input.fileFormat, input.opts), input.files)
+		if err != nil {
+			return 0, err
+		}
+		size += n
+	}
+	return size, nil
+}
+
+// confirmEstimate prints the estimated table size for --estimate. When it exceeds the free space of
+// dir, loading continues only if the user confirms on a terminal.
+func confirmEstimate(dir string, inputs []inputTable) error {
+	need, err := estimateTableSizeFromMetadata(inputs)
+	if err != nil {
+		return err
+	}
+	free, ok := freeDiskSpace(dir)
+	if !ok {
+		fmt.Fprintf(statusOut, "Estimated table size: %s\n", formatBytes(need))
+		return nil
+	}
+	fmt.Fprintf(statusOut, "Estimated table size: %s (%s free in %s)\n", formatBytes(need), formatBytes(free), dir)
+	if free >= need {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return checkFreeSpace(dir, need)
+	}
+
+	fmt.Fprintf(os.Stderr, "The table may not fit into the free space of %s. Load it anyway? [y/N] ", dir)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return fmt.Errorf("not loading the table")
+	}
+}
+
+// checkTableSpace checks that the tables loaded from the inputs fit into the free space of dir, asking
+// for confirmation with --estimate. Without --estimate only the temporary directory is checked, the
+// size of the inputs says little about a --database that may already hold other tables.
+func checkTableSpace(cmd *cobra.Command, dir string, inputs []inputTable) error {
+	if cmd.Flag("estimate").Value.String() == "true" {
+		return confirmEstimate(dir, inputs)
+	}
+	if cmd.Flag("database").Value.String() != "" {
+		return nil
+	}
+	return checkFreeSpace(dir, estimateTableSize(inputs))
+}
+
+// largestInput returns the input with the largest estimated size
+func largestInput(inputs []inputTable) inputTable {
+	largest := inputs[0]
+	for _, in := range inputs[1:] {
+		if estimateTableSize([]inputTable{in}) > estimateTableSize([]inputTable{largest}) {
+			largest = in
+		}
+	}
+	return largest
+}
+
+// checkFreeSpace returns an error when the file system of dir has less free space than the table is
+// estimated to need, instead of DuckDB failing halfway through the load. Platforms where the free space
+// cannot be determined are not checked.
+func checkFreeSpace(dir string, need int64) error {
+	free, ok := freeDiskSpace(dir)
+	if !ok || free >= need {
+		return nil
+	}
+	return fmt.Errorf("not enough free space in %s: loading the table needs about %s but only %s are free "+
+		"(use --temp-dir or --database to load it elsewhere)", dir, formatBytes(need), formatBytes(free))
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// duckType is a parsed DuckDB logical type as printed by DESCRIBE, e.g. "STRUCT(a INTEGER, b VARCHAR[])"
+type duckType struct {
+	Name   string      // upper-cased base name, e.g. INTEGER, DECIMAL, LIST, ARRAY, STRUCT, MAP
+	Args   []string    // raw parameters, e.g. precision and scale for DECIMAL or values for ENUM
+	Elem   *duckType   // element type for LIST and ARRAY, key and value for MAP are Fields[0] and Fields[1]
+	Size   int         // fixed length for ARRAY
+	Fields []typeField // members of STRUCT and UNION, key/value of MAP
+}
+
+// isNumeric reports whether the type holds integers or decimal or floating point numbers
+func (t *duckType) isNumeric() bool {
+	switch t.Name {
+	case "TINYINT", "INT1", "SMALLINT", "INT2", "INTEGER", "INT", "INT4", "BIGINT", "INT8", "HUGEINT",
+		"UTINYINT", "USMALLINT", "UINTEGER", "UBIGINT", "UHUGEINT",
+		"FLOAT", "REAL", "DOUBLE", "DECIMAL", "NUMERIC":
+		return true
+	default:
+		return false
+	}
+}
+
+// typeField is a named member of a nested type
+type typeField struct {
+	Name string
+	Type *duckType
+}
+
+// parseDuckType parses a DuckDB type string into its nested representation
+func parseDuckType(s string) (*duckType, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty type")
+	}
+
+	// LIST and ARRAY suffixes bind loosest, so they are peeled off first
+	if strings.HasSuffix(s, "]") {
+		open := strings.LastIndex(s, "[")
+		if open <= 0 {
+			return nil, fmt.Errorf("invalid type %q", s)
+		}
+		elem, err := parseDuckType(s[:open])
+		if err != nil {
+			return nil, err
+		}
+		size := s[open+1 : len(s)-1]
+		if size == "" {
+			return &duckType{Name: "LIST", Elem: elem}, nil
+		}
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array size in type %q", s)
+		}
+		return &duckType{Name: "ARRAY", Elem: elem, Size: n}, nil
+	}
+
+	open := strings.Index(s, "(")
+	if open < 0 {
+		return &duckType{Name: strings.ToUpper(s)}, nil
+	}
+	if !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("invalid type %q", s)
+	}
+
+	t := &duckType{Name: strings.ToUpper(strings.TrimSpace(s[:open]))}
+	params := splitTopLevel(s[open+1 : len(s)-1])
+
+	switch t.Name {
+	case "STRUCT", "UNION":
+		for _, p := range params {
+			name, rest, err := cutFieldName(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid member %q in type %q: %w", p, s, err)
+			}
+			ft, err := parseDuckType(rest)
+			if err != nil {
+				return nil, err
+			}
+			t.Fields = append(t.Fields, typeField{Name: name, Type: ft})
+		}
+	case "MAP":
+		if len(params) != 2 {
+			return nil, fmt.Errorf("invalid type %q: MAP needs a key and a value type", s)
+		}
+		key, err := parseDuckType(params[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := parseDuckType(params[1])
+		if err != nil {
+			return nil, err
+		}
+		t.Fields = []typeField{{Name: "key", Type: key}, {Name: "value", Type: value}}
+	default:
+		t.Args = params
+	}
+	return t, nil
+}
+
+// splitTopLevel splits s on commas that are not nested inside parentheses, brackets or quotes
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" {
+		parts = append(parts, rest)
+	}
+	return parts
+}
+
+// cutFieldName splits a struct member such as `"a b" INTEGER` into its unquoted name and type
+func cutFieldName(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, `"`) {
+		name, rest, ok := strings.Cut(s, " ")
+		if !ok {
+			return "", "", fmt.Errorf("missing type")
+		}
+		return name, rest, nil
+	}
+
+	// Quoted names escape embedded quotes by doubling them
+	var name strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '"' {
+			name.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '"' {
+			name.WriteByte('"')
+			i++
+			continue
+		}
+		return name.String(), strings.TrimSpace(s[i+1:]), nil
+	}
+	return "", "", fmt.Errorf("unterminated quoted name")
+}
+
+// unquoteLiteral strips the single quotes from a SQL string literal such as an ENUM value
+func unquoteLiteral(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var viewCmd = &cobra.Command{
+	Use:   "view <file or pattern>...",
+	Short: "Write a DuckDB view definition over the files",
+	Long: `Write a CREATE VIEW statement reading the files, without running it, so the files can be queried
+from other DuckDB sessions and tools. The view is named p, or after the files when several inputs are
+given, and reflects all flags that change how the files are read.`,
+	Example: `  dpi view data.parquet -o view.sql
+  dpi view -a -s data.csv | duckdb analysis.duckdb`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runViewCommand,
+}
+
+func init() {
+	addReadFlags(viewCmd)
+	viewCmd.Flags().StringP("output", "o", "", "File to write the view definition to (default: stdout)")
+	rootCmd.AddCommand(viewCmd)
+}
+
+func runViewCommand(cmd *cobra.Command, args []string) {
+	statusOut = os.Stderr
+
+	opts, err := readOptions(cmd)
+	if err != nil {
+		exitWithError("%v", err)
+	}
+
+	tempDir, err := createTempDirectory(cmd.Flag("temp-dir").Value.String())
+	if err != nil {
+		exitWithError("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var inputs []inputTable
+	for _, path := range args {
+		input, err := prepareInput(cmd, path, tempDir, opts)
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		// The view outlives dpi, so it cannot read copies from the temporary directory
+		for _, f := range input.files {
+			if strings.HasPrefix(f, tempDir) {
+				exitWithError("%s is read from a copy in dpi's temporary directory, which is removed when dpi exits, so it cannot be used in a view", path)
+			}
+		}
+		inputs = append(inputs, input)
+	}
+	if len(inputs) > 1 {
+		assignTableNames(inputs)
+	}
+
+	var statements []string
+	for _, input := range inputs {
+		query, err := buildSelectQuery(input.filename(), input.fileFormat, input.opts)
+		if err != nil {
+			exitWithError("%v", err)
+		}
+		statements = append(statements, viewExtensions(input.fileFormat, input.opts)+
+			fmt.Sprintf("CREATE VIEW %s AS %s;", quoteIdentifier(input.name), query))
+	}
+	definition := strings.Join(statements, "\n") + "\n"
+
+	output := cmd.Flag("output").Value.String()
+	if output == "" {
+		fmt.Fprint(os.Stdout, definition)
+		return
+	}
+	if err := os.WriteFile(output, []byte(definition), 0o644); err != nil {
+		exitWithError("Failed to write %s: %v", output, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d view definition(s) to %s\n", len(statements), output)
+}
+
+// viewExtensions returns the LOAD statements the view needs. Unlike sessionSetup it leaves out the S3
+// secret and the session settings, which would replace the credentials and settings of the session
+// loading the view.
+func viewExtensions(fileFormat FileFormat, opts tableOptions) string {
+	var load string
+	if ext := requiredExtension(fileFormat); ext != "" {
+		load += fmt.Sprintf("LOAD %s; ", ext)
+	}
+	if opts.remote {
+		load += "LOAD httpfs; "
+	}
+	return load
+}
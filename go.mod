@@ -2,8 +2,14 @@ module github.com/masa-fukui/dpi
 
 go 1.22.4
 
+require (
+	github.com/spf13/cobra v1.9.1
+	github.com/spf13/pflag v1.0.6
+	github.com/ulikunitz/xz v0.5.12
+	golang.org/x/term v0.28.0
+)
+
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/cobra v1.9.1 // indirect
-	github.com/spf13/pflag v1.0.6 // indirect
+	golang.org/x/sys v0.29.0 // indirect
 )